@@ -2,15 +2,34 @@ package main
 
 import (
 	"database/sql"
+	"flag"
 	_ "github.com/mattn/go-sqlite3"
 	"log"
 	"trainer/internal/pkg/auth"
 	"trainer/internal/pkg/draft"
+	"trainer/internal/pkg/judge"
 	"trainer/internal/pkg/problem"
 	"trainer/internal/pkg/server"
 )
 
+var (
+	scheduler      = flag.String("scheduler", "weekly", "Scheduling algorithm used for problems (weekly, sm2)")
+	leechThreshold = flag.Int64("leech-threshold", problem.DefaultLeechThreshold, "Failures after which a problem is a leech")
+	leechPolicy    = flag.String("leech-policy", problem.LeechKeep, "What happens to leeches (keep, suspend)")
+	judgeTimeout   = flag.Duration("judge-timeout", judge.DefaultTimeout, "Time all tests of a submission may run for")
+	judgeMemory    = flag.Uint64("judge-memory", judge.DefaultMemory, "Memory of a judge worker in megabytes")
+)
+
 func main() {
+	// The judge starts copies of the server as workers
+	if judge.IsWorker() {
+		if err := judge.RunWorker(); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+	flag.Parse()
+
 	s := server.New(":80")
 
 	db, err := sql.Open("sqlite3", "./data/trainer.db")
@@ -19,15 +38,63 @@ func main() {
 	}
 	defer db.Close()
 
-	a := auth.New(db, []string{"/app/", "/api/problem/", "/api/draft/"})
+	a := auth.New(db, []string{"/app/", "/api/problem/", "/api/draft/", "/api/settings/", "/api/schedule/", "/api/mock/"})
 	s.RegisterAuth(a)
 
 	// Register api routes
 	box := problem.NewBox(db)
+	if sched, ok := problem.Schedulers[*scheduler]; ok {
+		box.Scheduler = sched
+	} else {
+		log.Fatalf("Unknown scheduler %q", *scheduler)
+	}
+	if *leechPolicy != problem.LeechKeep && *leechPolicy != problem.LeechSuspend {
+		log.Fatalf("Unknown leech policy %q", *leechPolicy)
+	}
+	if *leechThreshold < 1 {
+		log.Fatalf("Leech threshold must be at least 1, got %d", *leechThreshold)
+	}
+	box.LeechThreshold = *leechThreshold
+	box.LeechPolicy = *leechPolicy
+	box.Judge = judge.New(*judgeTimeout, *judgeMemory)
 	s.RegisterApiFunc("/problem/update", box.ProblemUpdate)
 	s.RegisterApiFunc("/problem/submit", box.ProblemSubmit)
 	s.RegisterApiFunc("/problem/next", box.ProblemNext)
 	s.RegisterApiFunc("/problem/get", box.ProblemGet)
+	s.RegisterApiFunc("/problem/history", box.ProblemHistory)
+	s.RegisterApiFunc("/problem/leeches", box.ProblemLeeches)
+	s.RegisterApiFunc("/problem/tags", box.ProblemTags)
+	s.RegisterApiFunc("/problem/revisions", box.ProblemRevisions)
+	s.RegisterApiFunc("/problem/diff", box.ProblemDiff)
+	s.RegisterApiFunc("/problem/rollback", box.ProblemRollback)
+	s.RegisterApiFunc("/problem/suggest", box.ProblemSuggest)
+	s.RegisterApiFunc("/problem/suggestions", box.ProblemSuggestions)
+	s.RegisterApiFunc("/problem/suggestion/accept", box.SuggestionAccept)
+	s.RegisterApiFunc("/problem/suggestion/reject", box.SuggestionReject)
+	s.RegisterApiFunc("/problem/archive", box.ProblemArchive)
+	s.RegisterApiFunc("/problem/restore", box.ProblemRestore)
+	s.RegisterApiFunc("/problem/purge", box.ProblemPurge)
+	s.RegisterApiFunc("/problem/tests", box.ProblemTests)
+	s.RegisterApiFunc("/problem/tests/update", box.ProblemTestsUpdate)
+	s.RegisterApiFunc("/problem/results", box.SessionResults)
+	s.RegisterApiFunc("/problem/hints", box.ProblemHints)
+	s.RegisterApiFunc("/problem/hints/update", box.ProblemHintsUpdate)
+	s.RegisterApiFunc("/problem/hint", box.ProblemHint)
+	s.RegisterApiFunc("/problem/search", box.ProblemSearch)
+	s.RegisterApiFunc("/problem/list", box.ProblemList)
+	s.RegisterApiFunc("/problem/suspend", box.ProblemSuspend)
+	s.RegisterApiFunc("/problem/unsuspend", box.ProblemUnsuspend)
+	s.RegisterApiFunc("/problem/bury", box.ProblemBury)
+	s.RegisterApiFunc("/settings/get", box.SettingsGet)
+	s.RegisterApiFunc("/settings/update", box.SettingsUpdate)
+	s.RegisterApiFunc("/schedule/pause", box.SchedulePause)
+	s.RegisterApiFunc("/schedule/resume", box.ScheduleResume)
+	s.RegisterApiFunc("/schedule/forecast", box.ScheduleForecast)
+	s.RegisterApiFunc("/mock/start", box.MockStart)
+	s.RegisterApiFunc("/mock/next", box.MockNext)
+	s.RegisterApiFunc("/mock/submit", box.MockSubmit)
+	s.RegisterApiFunc("/mock/finish", box.MockFinish)
+	s.RegisterApiFunc("/mock/get", box.MockGet)
 
 	pad := draft.NewScratchPad(db)
 	s.RegisterApiFunc("/draft/update", pad.DraftUpdate)
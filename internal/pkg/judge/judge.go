@@ -0,0 +1,114 @@
+// Package judge runs JavaScript submissions
+// against the test cases of a problem. Each
+// submission runs in a worker process, a copy
+// of the server started in worker mode, which
+// is killed once its time is up and whose
+// memory is capped.
+
+package judge
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"os/exec"
+	"runtime"
+	"strconv"
+	"time"
+	"trainer/internal/pkg/problem"
+)
+
+var (
+	ErrTimeout = errors.New("Test timed out")
+	ErrOutput  = errors.New("Judge output is too large")
+)
+
+const (
+	DefaultTimeout = 10 * time.Second // Time all tests of a submission may run for
+	DefaultMemory  = 256              // Memory of a worker in megabytes
+	maxOutput      = 1 << 20          // Bytes a worker may write
+	workerEnv      = "TRAINER_JUDGE_MEMORY"
+)
+
+type JS struct {
+	timeout time.Duration
+	memory  uint64        // Address space limit of a worker in bytes
+	slots   chan struct{} // Bounds the number of workers running at once
+}
+
+// Work sent to a worker
+type job struct {
+	Code    string             `json:"code"`
+	Tests   []problem.TestCase `json:"tests"`
+	Timeout time.Duration      `json:"timeout"` // Time each test may run for
+}
+
+func New(timeout time.Duration, memory uint64) *JS {
+	var j JS
+	j.timeout = timeout
+	j.memory = memory << 20
+	j.slots = make(chan struct{}, runtime.NumCPU())
+	return &j
+}
+
+// Judge interface implementation
+
+func (j *JS) Run(code string, tests []problem.TestCase) ([]problem.TestResult, error) {
+	if len(tests) == 0 {
+		return nil, nil
+	}
+	j.slots <- struct{}{}
+	defer func() { <-j.slots }()
+
+	input, err := json.Marshal(job{code, tests, j.timeout / time.Duration(len(tests))})
+	if err != nil {
+		return nil, err
+	}
+	exe, err := os.Executable()
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), j.timeout)
+	defer cancel()
+	var output capped
+	cmd := exec.CommandContext(ctx, exe)
+	cmd.Env = append(os.Environ(), workerEnv+"="+strconv.FormatUint(j.memory, 10))
+	cmd.Stdin = bytes.NewReader(input)
+	cmd.Stdout = &output
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+	// A worker which crashes, runs out of memory or
+	// time fails all tests, as does garbled output
+	var results []problem.TestResult
+	if err := cmd.Wait(); ctx.Err() != nil {
+		return failed(tests, ErrTimeout.Error()), nil
+	} else if err != nil {
+		return failed(tests, "Judge failed: "+err.Error()), nil
+	} else if err := json.Unmarshal(output.Bytes(), &results); err != nil || len(results) != len(tests) {
+		return failed(tests, "Judge failed: invalid output"), nil
+	}
+	return results, nil
+}
+
+func failed(tests []problem.TestCase, reason string) []problem.TestResult {
+	results := make([]problem.TestResult, len(tests))
+	for i, test := range tests {
+		results[i] = problem.TestResult{Test: test.Id, Output: reason}
+	}
+	return results
+}
+
+// Buffer which refuses writes beyond maxOutput
+type capped struct {
+	bytes.Buffer
+}
+
+func (c *capped) Write(p []byte) (int, error) {
+	if c.Len()+len(p) > maxOutput {
+		return 0, ErrOutput
+	}
+	return c.Buffer.Write(p)
+}
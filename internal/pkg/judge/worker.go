@@ -0,0 +1,101 @@
+// Worker side of the judge, which runs the tests
+// of one submission in an embedded interpreter
+
+package judge
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"strconv"
+	"syscall"
+	"time"
+	"trainer/internal/pkg/problem"
+
+	"github.com/dop251/goja"
+)
+
+var (
+	ErrStringify = errors.New("JSON.stringify is not available")
+)
+
+// Reports if the process was started as a judge worker
+func IsWorker() bool {
+	return os.Getenv(workerEnv) != ""
+}
+
+// Judge the job on stdin and write the results to
+// stdout, the address space of the process is
+// limited first
+func RunWorker() error {
+	memory, err := strconv.ParseUint(os.Getenv(workerEnv), 10, 64)
+	if err != nil {
+		return err
+	}
+	if err := syscall.Setrlimit(syscall.RLIMIT_AS, &syscall.Rlimit{Cur: memory, Max: memory}); err != nil {
+		return err
+	}
+	var j job
+	if err := json.NewDecoder(os.Stdin).Decode(&j); err != nil {
+		return err
+	}
+	results := make([]problem.TestResult, len(j.Tests))
+	for i, test := range j.Tests {
+		results[i] = runTest(j.Code, test, time.Now().Add(j.Timeout))
+	}
+	return json.NewEncoder(os.Stdout).Encode(results)
+}
+
+// Run one test, the expected value is computed in
+// its own interpreter, so the submission can not
+// change it. Both values are compared in Go.
+func runTest(code string, test problem.TestCase, deadline time.Time) (res problem.TestResult) {
+	res.Test = test.Id
+	want, err := evaluate("", test.Expected, deadline)
+	if err != nil {
+		res.Output = "Invalid test: " + err.Error()
+		return
+	}
+	got, err := evaluate(code, test.Input, deadline)
+	if err != nil {
+		res.Output = err.Error()
+		return
+	}
+	res.Output = got
+	res.Passed = got == want
+	return
+}
+
+// Run code in a fresh interpreter, then return the JSON
+// of expr. JSON.stringify is taken before the code runs,
+// so the code can not replace it.
+func evaluate(code, expr string, deadline time.Time) (string, error) {
+	vm := goja.New()
+	stringify, ok := goja.AssertFunction(vm.Get("JSON").ToObject(vm).Get("stringify"))
+	if !ok {
+		return "", ErrStringify
+	}
+	// Logs are not judged, like in the run worker
+	console := vm.NewObject()
+	console.Set("log", func(goja.FunctionCall) goja.Value {
+		return goja.Undefined()
+	})
+	vm.Set("console", console)
+	timer := time.AfterFunc(time.Until(deadline), func() {
+		vm.Interrupt(ErrTimeout)
+	})
+	defer timer.Stop()
+
+	if _, err := vm.RunString(code); err != nil {
+		return "", err
+	}
+	value, err := vm.RunString(expr)
+	if err != nil {
+		return "", err
+	}
+	json, err := stringify(goja.Undefined(), value)
+	if err != nil {
+		return "", err
+	}
+	return json.String(), nil
+}
@@ -0,0 +1,64 @@
+// Problems can be archived, which removes
+// them from rotation but keeps their history.
+// Admins can purge problems for good.
+
+package problem
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+var (
+	ErrArchived = errors.New("Problem is archived")
+)
+
+// Load a problem the user may edit
+func (b *Box) ownProblem(r *http.Request, user int64) (Problem, error) {
+	id, err := strconv.ParseInt(r.FormValue("id"), 10, 64)
+	if err != nil {
+		return Problem{}, err
+	}
+	p, err := b.getProblem(id)
+	if err == sql.ErrNoRows {
+		return p, ErrProblemNotExists
+	} else if err != nil {
+		return p, err
+	} else if !canEdit(p, user) {
+		return p, ErrForbidden
+	}
+	return p, nil
+}
+
+// Implement server api functions
+
+func (b *Box) ProblemArchive(r *http.Request, user int64) (interface{}, error) {
+	p, err := b.ownProblem(r, user)
+	if err != nil {
+		return nil, err
+	}
+	return p.Id, b.setArchived(p.Id, time.Now().Unix())
+}
+
+func (b *Box) ProblemRestore(r *http.Request, user int64) (interface{}, error) {
+	p, err := b.ownProblem(r, user)
+	if err != nil {
+		return nil, err
+	}
+	return p.Id, b.setArchived(p.Id, 0)
+}
+
+func (b *Box) ProblemPurge(r *http.Request, user int64) (interface{}, error) {
+	// Delete problem with its sessions, only for admins
+	if !isAdmin(user) {
+		return nil, ErrForbidden
+	}
+	p, err := b.ownProblem(r, user)
+	if err != nil {
+		return nil, err
+	}
+	return p.Id, b.purgeProblem(p.Id)
+}
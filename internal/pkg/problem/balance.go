@@ -0,0 +1,60 @@
+// Spread due dates over nearby days, so problems
+// solved together do not come due together
+
+package problem
+
+import (
+	"crypto/rand"
+	"math/big"
+	"time"
+)
+
+const (
+	fuzzThreshold = 3   // Intervals of at least this many days are spread
+	fuzzRate      = 0.1 // Intervals move by up to this fraction
+	maxFuzz       = 7   // Intervals move by at most this many days
+)
+
+// Range of intervals a schedule may be moved to
+func fuzzRange(interval int64) (min, max int64) {
+	fuzz := int64(float64(interval)*fuzzRate + 0.5)
+	if fuzz < 1 {
+		fuzz = 1
+	} else if fuzz > maxFuzz {
+		fuzz = maxFuzz
+	}
+	return interval - fuzz, interval + fuzz
+}
+
+// Move the interval of the schedule to the day within
+// its fuzz range that has the fewest problems due for
+// the user. Ties are broken at random.
+func (b *Box) balanceDue(sched Schedule, settings Settings, reviewed time.Time) (Schedule, error) {
+	if sched.Interval < fuzzThreshold {
+		return sched, nil
+	}
+	min, max := fuzzRange(sched.Interval)
+	start := settings.startOfDay(reviewed)
+	var candidates []int64
+	var least int64 = -1
+	for days := min; days <= max; days++ {
+		from := start.AddDate(0, 0, int(days))
+		load, err := b.countDue(sched.User, sched.Problem, from.Unix(), from.AddDate(0, 0, 1).Unix())
+		if err != nil {
+			return sched, err
+		}
+		if least == -1 || load < least {
+			least = load
+			candidates = candidates[:0]
+		}
+		if load == least {
+			candidates = append(candidates, days)
+		}
+	}
+	i, err := rand.Int(rand.Reader, big.NewInt(int64(len(candidates))))
+	if err != nil {
+		return sched, err
+	}
+	sched.Interval = candidates[i.Int64()]
+	return sched, nil
+}
@@ -6,14 +6,25 @@ package problem
 import (
 	"database/sql"
 	"errors"
+	"strings"
 	"time"
 )
 
 var (
 	ErrEmpty            = errors.New("Values may not be empty")
 	ErrProblemNotExists = errors.New("Problem does not exist")
+	ErrTarget           = errors.New("Target time may not be negative")
 )
 
+// Comma separated tags of a problem
+const problemTags = `IFNULL(
+	(SELECT GROUP_CONCAT(tags.name) FROM problem_tags INNER JOIN tags ON tags.id = problem_tags.tag WHERE problem_tags.problem = problems.id),
+	''
+)`
+
+// Columns read by scanProblem
+const problemColumns = `problems.id, problems.title, problems.question, problems.solution, problems.starter, problems.target, problems.difficulty, IFNULL(problems.owner, 0), problems.archived, ` + problemTags
+
 func initDb(db *sql.DB) (err error) {
 	query := `
 	PRAGMA foreign_keys = ON;
@@ -22,7 +33,96 @@ func initDb(db *sql.DB) (err error) {
 		id INTEGER NOT NULL PRIMARY KEY AUTOINCREMENT,
 		title VARCHAR(64),
 		question TEXT NOT NULL,
-		solution TEXT NOT NULL
+		solution TEXT NOT NULL,
+		starter TEXT NOT NULL DEFAULT '',
+		target INTEGER NOT NULL DEFAULT 0,
+		difficulty INTEGER NOT NULL DEFAULT 0,
+		owner INTEGER,
+		archived INTEGER NOT NULL DEFAULT 0,
+		FOREIGN KEY (owner) REFERENCES users (id)
+	);
+
+	CREATE TABLE IF NOT EXISTS problem_revisions (
+		id INTEGER NOT NULL PRIMARY KEY AUTOINCREMENT,
+		problem INTEGER NOT NULL,
+		author INTEGER,
+		date INTEGER NOT NULL,
+		title VARCHAR(64),
+		question TEXT NOT NULL,
+		solution TEXT NOT NULL,
+		starter TEXT NOT NULL DEFAULT '',
+		target INTEGER NOT NULL,
+		difficulty INTEGER NOT NULL,
+		tags TEXT NOT NULL,
+		FOREIGN KEY (problem) REFERENCES problems (id),
+		FOREIGN KEY (author) REFERENCES users (id)
+	);
+
+	CREATE TABLE IF NOT EXISTS problem_suggestions (
+		id INTEGER NOT NULL PRIMARY KEY AUTOINCREMENT,
+		problem INTEGER NOT NULL,
+		author INTEGER NOT NULL,
+		date INTEGER NOT NULL,
+		status VARCHAR(16) NOT NULL,
+		title VARCHAR(64),
+		question TEXT NOT NULL,
+		solution TEXT NOT NULL,
+		starter TEXT NOT NULL DEFAULT '',
+		target INTEGER NOT NULL,
+		difficulty INTEGER NOT NULL,
+		tags TEXT NOT NULL,
+		FOREIGN KEY (problem) REFERENCES problems (id),
+		FOREIGN KEY (author) REFERENCES users (id)
+	);
+
+	CREATE TABLE IF NOT EXISTS problem_tests (
+		id INTEGER NOT NULL PRIMARY KEY AUTOINCREMENT,
+		problem INTEGER NOT NULL,
+		position INTEGER NOT NULL,
+		input TEXT NOT NULL,
+		expected TEXT NOT NULL,
+		retired INTEGER NOT NULL DEFAULT 0,
+		FOREIGN KEY (problem) REFERENCES problems (id)
+	);
+
+	CREATE TABLE IF NOT EXISTS session_results (
+		id INTEGER NOT NULL PRIMARY KEY AUTOINCREMENT,
+		session INTEGER NOT NULL,
+		test INTEGER NOT NULL,
+		passed INTEGER NOT NULL,
+		output TEXT NOT NULL,
+		FOREIGN KEY (session) REFERENCES sessions (id),
+		FOREIGN KEY (test) REFERENCES problem_tests (id)
+	);
+
+	CREATE TABLE IF NOT EXISTS problem_hints (
+		id INTEGER NOT NULL PRIMARY KEY AUTOINCREMENT,
+		problem INTEGER NOT NULL,
+		position INTEGER NOT NULL,
+		text TEXT NOT NULL,
+		FOREIGN KEY (problem) REFERENCES problems (id)
+	);
+
+	CREATE TABLE IF NOT EXISTS hint_usage (
+		problem INTEGER NOT NULL,
+		user INTEGER NOT NULL,
+		used INTEGER NOT NULL,
+		PRIMARY KEY (problem, user),
+		FOREIGN KEY (problem) REFERENCES problems (id),
+		FOREIGN KEY (user) REFERENCES users (id)
+	);
+
+	CREATE TABLE IF NOT EXISTS tags (
+		id INTEGER NOT NULL PRIMARY KEY AUTOINCREMENT,
+		name VARCHAR(32) NOT NULL UNIQUE
+	);
+
+	CREATE TABLE IF NOT EXISTS problem_tags (
+		problem INTEGER NOT NULL,
+		tag INTEGER NOT NULL,
+		PRIMARY KEY (problem, tag),
+		FOREIGN KEY (problem) REFERENCES problems (id),
+		FOREIGN KEY (tag) REFERENCES tags (id)
 	);
 
 	CREATE TABLE IF NOT EXISTS schedule (
@@ -30,6 +130,12 @@ func initDb(db *sql.DB) (err error) {
 		problem INTEGER NOT NULL,
 		user INTEGER NOT NULL,
 		due INTEGER NOT NULL,
+		ease REAL NOT NULL DEFAULT 2.5,
+		interval INTEGER NOT NULL DEFAULT 0,
+		reps INTEGER NOT NULL DEFAULT 0,
+		lapses INTEGER NOT NULL DEFAULT 0,
+		suspended INTEGER NOT NULL DEFAULT 0,
+		buried INTEGER NOT NULL DEFAULT 0,
 		FOREIGN KEY (problem) REFERENCES problems (id),
 		FOREIGN KEY (user) REFERENCES users (id)
 	);
@@ -42,59 +148,403 @@ func initDb(db *sql.DB) (err error) {
 		code TEXT NOT NULL,
 		time INTEGER NOT NULL,
 		solved INTEGER NOT NULL,
+		grade INTEGER NOT NULL,
+		practice INTEGER NOT NULL DEFAULT 0,
+		hints INTEGER NOT NULL DEFAULT 0,
 		FOREIGN KEY (problem) REFERENCES problems (id),
 		FOREIGN KEY (user) REFERENCES users (id)
 	);
+
+	CREATE TABLE IF NOT EXISTS mocks (
+		id INTEGER NOT NULL PRIMARY KEY AUTOINCREMENT,
+		user INTEGER NOT NULL,
+		started INTEGER NOT NULL,
+		deadline INTEGER NOT NULL,
+		finished INTEGER NOT NULL DEFAULT 0,
+		count INTEGER NOT NULL,
+		score INTEGER NOT NULL DEFAULT 0,
+		FOREIGN KEY (user) REFERENCES users (id)
+	);
+
+	CREATE TABLE IF NOT EXISTS mock_problems (
+		id INTEGER NOT NULL PRIMARY KEY AUTOINCREMENT,
+		mock INTEGER NOT NULL,
+		position INTEGER NOT NULL,
+		problem INTEGER NOT NULL,
+		session INTEGER,
+		FOREIGN KEY (mock) REFERENCES mocks (id),
+		FOREIGN KEY (problem) REFERENCES problems (id),
+		FOREIGN KEY (session) REFERENCES sessions (id)
+	);
+
+	CREATE TABLE IF NOT EXISTS settings (
+		user INTEGER NOT NULL PRIMARY KEY,
+		reviews_per_day INTEGER NOT NULL DEFAULT 0,
+		new_per_day INTEGER NOT NULL DEFAULT 0,
+		paused INTEGER NOT NULL DEFAULT 0,
+		timezone VARCHAR(64) NOT NULL DEFAULT '',
+		day_start INTEGER NOT NULL DEFAULT 0,
+		FOREIGN KEY (user) REFERENCES users (id)
+	);
 	`
-	_, err = db.Exec(query)
-	return
+	if _, err = db.Exec(query); err != nil {
+		return
+	}
+	return migrateDb(db)
+}
+
+// Create the search index, which needs SQLite to be
+// built with FTS5. Without it search is disabled. The
+// index is rebuilt, as problems may have changed
+// while it was disabled.
+func initSearch(db *sql.DB) (bool, error) {
+	query := `
+	CREATE VIRTUAL TABLE IF NOT EXISTS problems_fts USING fts5 (
+		title,
+		question,
+		solution
+	);
+	`
+	if _, err := db.Exec(query); err != nil && strings.Contains(err.Error(), "no such module: fts5") {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+	query = `
+	DELETE FROM problems_fts;
+	INSERT INTO problems_fts (rowid, title, question, solution) SELECT id, title, question, solution FROM problems;
+	`
+	_, err := db.Exec(query)
+	return err == nil, err
+}
+
+func migrateDb(db *sql.DB) error {
+	// Schedules created before the SM-2 scheduler
+	// keep their due date, their repetitions are
+	// recovered from the session history.
+	if added, err := addColumn(db, "schedule", "ease", "REAL NOT NULL DEFAULT 2.5"); err != nil {
+		return err
+	} else if added {
+		query := `
+		ALTER TABLE schedule ADD COLUMN interval INTEGER NOT NULL DEFAULT 0;
+		ALTER TABLE schedule ADD COLUMN reps INTEGER NOT NULL DEFAULT 0;
+
+		UPDATE schedule SET reps = (
+			SELECT COUNT(*) FROM sessions WHERE solved = 1 AND problem = schedule.problem AND user = schedule.user AND date > IFNULL(
+				(SELECT date FROM sessions WHERE solved != 1 AND problem = schedule.problem AND user = schedule.user ORDER BY date DESC LIMIT 1),
+				0
+			)
+		);
+		UPDATE schedule SET interval = 7 * reps;
+		`
+		if _, err := db.Exec(query); err != nil {
+			return err
+		}
+	}
+	// Lapses of existing schedules are recovered
+	// from the failed sessions
+	if added, err := addColumn(db, "schedule", "lapses", "INTEGER NOT NULL DEFAULT 0"); err != nil {
+		return err
+	} else if added {
+		query := `
+		UPDATE schedule SET lapses = (
+			SELECT COUNT(*) FROM sessions WHERE solved != 1 AND problem = schedule.problem AND user = schedule.user
+		);
+		`
+		if _, err := db.Exec(query); err != nil {
+			return err
+		}
+	}
+	if _, err := addColumn(db, "schedule", "suspended", "INTEGER NOT NULL DEFAULT 0"); err != nil {
+		return err
+	}
+	if _, err := addColumn(db, "schedule", "buried", "INTEGER NOT NULL DEFAULT 0"); err != nil {
+		return err
+	}
+	if _, err := addColumn(db, "problems", "target", "INTEGER NOT NULL DEFAULT 0"); err != nil {
+		return err
+	}
+	if _, err := addColumn(db, "problems", "difficulty", "INTEGER NOT NULL DEFAULT 0"); err != nil {
+		return err
+	}
+	if _, err := addColumn(db, "problems", "archived", "INTEGER NOT NULL DEFAULT 0"); err != nil {
+		return err
+	}
+	if _, err := addColumn(db, "problems", "starter", "TEXT NOT NULL DEFAULT ''"); err != nil {
+		return err
+	}
+	if _, err := addColumn(db, "problem_revisions", "starter", "TEXT NOT NULL DEFAULT ''"); err != nil {
+		return err
+	}
+	if _, err := addColumn(db, "problem_suggestions", "starter", "TEXT NOT NULL DEFAULT ''"); err != nil {
+		return err
+	}
+	// Owners of existing problems are taken from their
+	// first revision, if the author is known
+	if added, err := addColumn(db, "problems", "owner", "INTEGER REFERENCES users (id)"); err != nil {
+		return err
+	} else if added {
+		query := `
+		UPDATE problems SET owner = (
+			SELECT author FROM problem_revisions WHERE problem = problems.id ORDER BY id ASC LIMIT 1
+		);
+		`
+		if _, err := db.Exec(query); err != nil {
+			return err
+		}
+	}
+	if _, err := addColumn(db, "settings", "paused", "INTEGER NOT NULL DEFAULT 0"); err != nil {
+		return err
+	}
+	if _, err := addColumn(db, "settings", "timezone", "VARCHAR(64) NOT NULL DEFAULT ''"); err != nil {
+		return err
+	}
+	if _, err := addColumn(db, "settings", "day_start", "INTEGER NOT NULL DEFAULT 0"); err != nil {
+		return err
+	}
+	// Sessions created before grades existed
+	// only know whether they were solved
+	if added, err := addColumn(db, "sessions", "grade", "INTEGER NOT NULL DEFAULT 0"); err != nil {
+		return err
+	} else if added {
+		query := `
+		UPDATE sessions SET grade = CASE WHEN solved = 1 THEN ? ELSE ? END;
+		`
+		if _, err := db.Exec(query, GradeGood, GradeAgain); err != nil {
+			return err
+		}
+	}
+	if _, err := addColumn(db, "sessions", "practice", "INTEGER NOT NULL DEFAULT 0"); err != nil {
+		return err
+	}
+	if _, err := addColumn(db, "sessions", "hints", "INTEGER NOT NULL DEFAULT 0"); err != nil {
+		return err
+	}
+	if _, err := addColumn(db, "problem_tests", "retired", "INTEGER NOT NULL DEFAULT 0"); err != nil {
+		return err
+	}
+	// Problems created before revisions existed get
+	// an initial revision without author and date
+	query := `
+	INSERT INTO problem_revisions (problem, title, question, solution, starter, target, difficulty, tags, author, date)
+	SELECT id, title, question, solution, starter, target, difficulty, ` + problemTags + `, NULL, 0 FROM problems WHERE NOT id IN (
+		SELECT problem FROM problem_revisions
+	);
+	`
+	_, err := db.Exec(query)
+	return err
 }
 
-func (b *Box) createProblem(p Problem) (Problem, error) {
+// Add a column to an existing table, unless
+// the column is already present. Reports if
+// the column was added.
+func addColumn(db *sql.DB, table, column, def string) (bool, error) {
+	rows, err := db.Query("PRAGMA table_info(" + table + ");")
+	if err != nil {
+		return false, err
+	}
+	defer rows.Close()
+	var cid, notNull, pk int
+	var name, kind string
+	var value sql.NullString
+	for rows.Next() {
+		if err := rows.Scan(&cid, &name, &kind, &notNull, &value, &pk); err != nil {
+			return false, err
+		}
+		if name == column {
+			return false, nil
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return false, err
+	}
+	rows.Close()
+	_, err = db.Exec("ALTER TABLE " + table + " ADD COLUMN " + column + " " + def + ";")
+	return err == nil, err
+}
+
+// Create a problem, unless it is likely a duplicate
+// of an existing one and the creation is not forced.
+// Likely duplicates are returned with ErrDuplicate.
+func (b *Box) createProblem(p Problem, user int64, force bool) (Problem, []Duplicate, error) {
 	if p.Title == "" || p.Question == "" || p.Solution == "" {
-		return p, ErrEmpty
+		return p, nil, ErrEmpty
+	}
+	if !force {
+		if duplicates, err := b.findDuplicates(p); err != nil {
+			return p, nil, err
+		} else if len(duplicates) > 0 {
+			return p, duplicates, ErrDuplicate
+		}
 	}
 	query := `
-	INSERT INTO problems (title, question, solution) VALUES (?, ?, ?);`
-	if res, err := b.db.Exec(query, p.Title, p.Question, p.Solution); err != nil {
-		return p, err
+	INSERT INTO problems (title, question, solution, starter, target, difficulty, owner) VALUES (?, ?, ?, ?, ?, ?, ?);`
+	if res, err := b.db.Exec(query, p.Title, p.Question, p.Solution, p.Starter, p.Target, p.Difficulty, user); err != nil {
+		return p, nil, err
 	} else {
 		p.Id, _ = res.LastInsertId()
-		return p, err
+		p.Owner = user
+		if err := b.setTags(p.Id, p.Tags); err != nil {
+			return p, nil, err
+		} else if err := b.indexProblem(p); err != nil {
+			return p, nil, err
+		}
+		return p, nil, b.storeRevision(p, user)
 	}
 }
 
-func (b *Box) updateProblem(p Problem) error {
+func (b *Box) updateProblem(p Problem, user int64) error {
 	if p.Title == "" || p.Question == "" || p.Solution == "" {
 		return ErrEmpty
 	}
-	query := `UPDATE problems SET title = ?, question = ?, solution = ? WHERE id = ?;`
-	if res, err := b.db.Exec(query, p.Title, p.Question, p.Solution, p.Id, p.Id); err != nil {
+	query := `UPDATE problems SET title = ?, question = ?, solution = ?, starter = ?, target = ?, difficulty = ? WHERE id = ?;`
+	if res, err := b.db.Exec(query, p.Title, p.Question, p.Solution, p.Starter, p.Target, p.Difficulty, p.Id); err != nil {
 		return err
 	} else if n, err := res.RowsAffected(); err != nil {
 		return err
 	} else if n == 0 {
 		return ErrProblemNotExists
 	}
-	return nil
+	if err := b.setTags(p.Id, p.Tags); err != nil {
+		return err
+	} else if err := b.indexProblem(p); err != nil {
+		return err
+	}
+	return b.storeRevision(p, user)
+}
+
+func (b *Box) storeRevision(p Problem, user int64) (err error) {
+	query := `
+	INSERT INTO problem_revisions (problem, author, date, title, question, solution, starter, target, difficulty, tags) VALUES (
+		?, ?, ?, ?, ?, ?, ?, ?, ?, ?
+	);
+	`
+	_, err = b.db.Exec(query, p.Id, user, time.Now().Unix(), p.Title, p.Question, p.Solution, p.Starter, p.Target, p.Difficulty, strings.Join(p.Tags, ","))
+	return
+}
+
+// Columns read by scanRevision
+const revisionColumns = `id, IFNULL(author, 0), date, problem, title, question, solution, starter, target, difficulty, tags`
+
+func scanRevision(row scanner) (r Revision, err error) {
+	var tags string
+	p := &r.Problem
+	if err = row.Scan(&r.Id, &r.Author, &r.Date, &p.Id, &p.Title, &p.Question, &p.Solution, &p.Starter, &p.Target, &p.Difficulty, &tags); err == nil {
+		p.Tags, err = parseTags(tags)
+	}
+	return
+}
+
+func (b *Box) getRevision(id int64) (Revision, error) {
+	query := `
+	SELECT ` + revisionColumns + ` FROM problem_revisions WHERE id = ?;
+	`
+	return scanRevision(b.db.QueryRow(query, id))
+}
+
+func (b *Box) listRevisions(id int64) ([]Revision, error) {
+	query := `
+	SELECT ` + revisionColumns + ` FROM problem_revisions WHERE problem = ? ORDER BY id DESC;
+	`
+	list := []Revision{}
+	rows, err := b.db.Query(query, id)
+	if err != nil {
+		return list, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		rev, err := scanRevision(rows)
+		if err != nil {
+			return list, err
+		}
+		list = append(list, rev)
+	}
+	return list, rows.Err()
+}
+
+func (b *Box) setTags(id int64, tags []string) error {
+	tx, err := b.db.Begin()
+	if err != nil {
+		return err
+	}
+	query := `
+	DELETE FROM problem_tags WHERE problem = ?;
+	`
+	if _, err := tx.Exec(query, id); err != nil {
+		tx.Rollback()
+		return err
+	}
+	query = `
+	INSERT OR IGNORE INTO tags (name) VALUES (?);
+	INSERT INTO problem_tags (problem, tag) SELECT ?, id FROM tags WHERE name = ?;
+	`
+	for _, tag := range tags {
+		if _, err := tx.Exec(query, tag, id, tag); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+func (b *Box) listTags() ([]TagCount, error) {
+	query := `
+	SELECT tags.name, COUNT(*) FROM tags
+	INNER JOIN problem_tags ON problem_tags.tag = tags.id
+	INNER JOIN problems ON problems.id = problem_tags.problem
+	WHERE problems.archived = 0
+	GROUP BY tags.id ORDER BY tags.name ASC;
+	`
+	list := []TagCount{}
+	rows, err := b.db.Query(query)
+	if err != nil {
+		return list, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var t TagCount
+		if err = rows.Scan(&t.Name, &t.Problems); err != nil {
+			return list, err
+		}
+		list = append(list, t)
+	}
+	return list, rows.Err()
 }
 
 func (b *Box) getProblem(id int64) (p Problem, err error) {
 	query := `
-	SELECT id, title, question, solution FROM problems WHERE id = ?;
+	SELECT ` + problemColumns + ` FROM problems WHERE id = ?;
 	`
-	row := b.db.QueryRow(query, id)
-	err = row.Scan(&p.Id, &p.Title, &p.Question, &p.Solution)
+	return scanProblem(b.db.QueryRow(query, id))
+}
+
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// Scan problemColumns, followed by any
+// extra columns
+func scanProblem(row scanner, extra ...interface{}) (p Problem, err error) {
+	var tags string
+	dest := []interface{}{&p.Id, &p.Title, &p.Question, &p.Solution, &p.Starter, &p.Target, &p.Difficulty, &p.Owner, &p.Archived, &tags}
+	if err = row.Scan(append(dest, extra...)...); err == nil {
+		p.Tags, err = parseTags(tags)
+		p.QuestionHTML = renderMarkdown(p.Question)
+		p.SolutionHTML = renderMarkdown(p.Solution)
+	}
 	return
 }
 
-func (b *Box) storeSession(s Session) (err error) {
+func (b *Box) storeSession(s Session) (id int64, err error) {
 	if s.Time < 1 || s.Code == "" {
 		err = ErrEmpty
 		return
 	}
 	query := `
-	INSERT INTO sessions (problem, user, date, code, time, solved) values (
+	INSERT INTO sessions (problem, user, date, code, time, solved, grade, practice, hints) values (
+		?,
+		?,
+		?,
 		?,
 		?,
 		?,
@@ -103,54 +553,802 @@ func (b *Box) storeSession(s Session) (err error) {
 		?
 	);
 	`
-	_, err = b.db.Exec(query, s.Problem, s.User, s.Date, s.Code, s.Time, s.Solved)
+	res, err := b.db.Exec(query, s.Problem, s.User, s.Date, s.Code, s.Time, s.Solved, s.Grade, s.Practice, s.Hints)
+	if err != nil {
+		return
+	}
+	return res.LastInsertId()
+}
+
+func (b *Box) sessionHistory(id, user int64) ([]Session, error) {
+	query := `
+	SELECT id, problem, user, date, code, time, solved, grade, practice, hints FROM sessions WHERE problem = ? AND user = ? ORDER BY date ASC, id ASC;
+	`
+	var history []Session
+	rows, err := b.db.Query(query, id, user)
+	if err != nil {
+		return history, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var s Session
+		if err = rows.Scan(&s.Id, &s.Problem, &s.User, &s.Date, &s.Code, &s.Time, &s.Solved, &s.Grade, &s.Practice, &s.Hints); err != nil {
+			return history, err
+		}
+		history = append(history, s)
+	}
+	return history, rows.Err()
+}
+
+func (b *Box) getSchedule(id, user int64) (s Schedule, err error) {
+	query := `
+	SELECT problem, user, due, ease, interval, reps, lapses, suspended, buried FROM schedule WHERE problem = ? AND user = ?;
+	`
+	row := b.db.QueryRow(query, id, user)
+	err = row.Scan(&s.Problem, &s.User, &s.Due, &s.Ease, &s.Interval, &s.Reps, &s.Lapses, &s.Suspended, &s.Buried)
 	return
 }
 
-func (b *Box) numSuccessfulAttempts(id, user int64) (num int) {
+func (b *Box) scheduleProblem(s Schedule) (err error) {
 	query := `
-	SELECT COUNT(*) FROM sessions WHERE solved = 1 AND problem = ? AND user = ? AND date > IFNULL(
-		(SELECT date FROM sessions WHERE solved != 1 AND problem = ? AND user = ? ORDER BY date DESC LIMIT 1),
-		0
+	DELETE FROM schedule WHERE problem = ? AND user = ?;
+
+	INSERT INTO schedule (problem, user, due, ease, interval, reps, lapses, suspended, buried) VALUES (
+		?,
+		?,
+		?,
+		?,
+		?,
+		?,
+		?,
+		?,
+		?
 	);
 	`
-	// On error, num is 0
-	b.db.QueryRow(query, id, user, id, user).Scan(&num)
+	_, err = b.db.Exec(query, s.Problem, s.User, s.Problem, s.User, s.Due, s.Ease, s.Interval, s.Reps, s.Lapses, s.Suspended, s.Buried)
 	return
 }
 
-func (b *Box) scheduleProblem(id, user, due int64) (err error) {
+func (b *Box) unscheduleProblem(id, user int64) (err error) {
 	query := `
 	DELETE FROM schedule WHERE problem = ? AND user = ?;
+	`
+	_, err = b.db.Exec(query, id, user)
+	return
+}
+
+// Both queries take an optional tag, an
+// empty tag matches all problems. Archived
+// problems are never served.
 
-	INSERT INTO schedule (problem, user, due) VALUES (
+func (b *Box) nextScheduledProblem(user int64, tag string) (p Problem, err error) {
+	query := `
+	SELECT ` + problemColumns + ` FROM problems WHERE id = (
+		SELECT problem FROM schedule WHERE due <= ? AND user = ? AND suspended = 0 AND buried <= ? AND problem IN (
+			SELECT id FROM problems WHERE archived = 0
+		) AND (? = '' OR problem IN (
+			SELECT problem_tags.problem FROM problem_tags INNER JOIN tags ON tags.id = problem_tags.tag WHERE tags.name = ?
+		)) ORDER BY due ASC LIMIT 1
+	);
+	`
+	now := time.Now().Unix()
+	return scanProblem(b.db.QueryRow(query, now, user, now, tag, tag))
+}
+
+func (b *Box) notScheduledProblem(user int64, tag string, max Difficulty) (p Problem, err error) {
+	// Suspended or buried problems always have a schedule,
+	// problems harder than max are only served if there
+	// are no others left
+	query := `
+	SELECT ` + problemColumns + ` FROM problems WHERE archived = 0 AND NOT id IN (
+		SELECT problem FROM schedule WHERE user = ?
+	) AND (? = '' OR id IN (
+		SELECT problem_tags.problem FROM problem_tags INNER JOIN tags ON tags.id = problem_tags.tag WHERE tags.name = ?
+	)) ORDER BY difficulty > ? ASC, RANDOM() LIMIT 1;
+	`
+	return scanProblem(b.db.QueryRow(query, user, tag, tag, max))
+}
+
+// Count the users most recent sessions
+// and how many of them were solved
+func (b *Box) recentSuccess(user, limit int64) (sessions, solved int64, err error) {
+	query := `
+	SELECT COUNT(*), IFNULL(SUM(solved), 0) FROM (
+		SELECT solved FROM sessions WHERE user = ? AND practice = 0 ORDER BY date DESC LIMIT ?
+	);
+	`
+	row := b.db.QueryRow(query, user, limit)
+	err = row.Scan(&sessions, &solved)
+	return
+}
+
+// Count the problems attempted today, split into
+// reviews and problems attempted for the first time
+func (b *Box) countToday(user int64, since int64) (reviews, fresh int64, err error) {
+	query := `
+	SELECT
+		COUNT(DISTINCT CASE WHEN seen THEN problem END),
+		COUNT(DISTINCT CASE WHEN seen THEN NULL ELSE problem END)
+	FROM (
+		SELECT problem, EXISTS (
+			SELECT 1 FROM sessions AS past WHERE past.user = today.user AND past.problem = today.problem AND past.date < ?
+		) AS seen FROM sessions AS today WHERE user = ? AND date >= ? AND practice = 0
+	);
+	`
+	row := b.db.QueryRow(query, since, user, since)
+	err = row.Scan(&reviews, &fresh)
+	return
+}
+
+func (b *Box) getSettings(user int64) (s Settings, err error) {
+	query := `
+	SELECT reviews_per_day, new_per_day, paused, timezone, day_start FROM settings WHERE user = ?;
+	`
+	row := b.db.QueryRow(query, user)
+	if err = row.Scan(&s.ReviewsPerDay, &s.NewPerDay, &s.Paused, &s.Timezone, &s.DayStart); err == sql.ErrNoRows {
+		// Users start with the defaults
+		return Settings{}, nil
+	}
+	return
+}
+
+func (b *Box) updateSettings(user int64, s Settings) (err error) {
+	query := `
+	INSERT OR REPLACE INTO settings (user, reviews_per_day, new_per_day, paused, timezone, day_start) VALUES (
+		?,
+		?,
+		?,
 		?,
 		?,
 		?
 	);
 	`
-	_, err = b.db.Exec(query, id, user, id, user, due)
+	_, err = b.db.Exec(query, user, s.ReviewsPerDay, s.NewPerDay, s.Paused, s.Timezone, s.DayStart)
 	return
 }
 
-func (b *Box) nextScheduledProblem(user int64) (p Problem, err error) {
+// Count the other problems of the user which
+// are due in the given time span
+func (b *Box) countDue(user, id, from, to int64) (num int64, err error) {
 	query := `
-	SELECT id, title, question, solution FROM problems WHERE id = (
-		SELECT problem FROM schedule WHERE due <= ? AND user = ? ORDER BY due ASC LIMIT 1
+	SELECT COUNT(*) FROM schedule WHERE user = ? AND problem != ? AND due >= ? AND due < ? AND suspended = 0 AND problem IN (
+		SELECT id FROM problems WHERE archived = 0
 	);
 	`
-	row := b.db.QueryRow(query, time.Now().Unix(), user)
-	err = row.Scan(&p.Id, &p.Title, &p.Question, &p.Solution)
+	row := b.db.QueryRow(query, user, id, from, to)
+	err = row.Scan(&num)
 	return
 }
 
-func (b *Box) notScheduledProblem(user int64) (p Problem, err error) {
+// List the due dates of the users problems
+// which are due before the given time
+func (b *Box) dueDates(user, before int64) ([]int64, error) {
 	query := `
-	SELECT id, title, question, solution FROM problems WHERE NOT id IN (
-		SELECT problem FROM schedule WHERE user = ?
-	) ORDER BY RANDOM() LIMIT 1;
+	SELECT due FROM schedule WHERE user = ? AND due < ? AND suspended = 0 AND problem IN (
+		SELECT id FROM problems WHERE archived = 0
+	) ORDER BY due ASC;
+	`
+	var dues []int64
+	rows, err := b.db.Query(query, user, before)
+	if err != nil {
+		return dues, err
+	}
+	defer rows.Close()
+	var due int64
+	for rows.Next() {
+		if err = rows.Scan(&due); err != nil {
+			return dues, err
+		}
+		dues = append(dues, due)
+	}
+	return dues, rows.Err()
+}
+
+// Drop the schedules of problems the user buried without
+// ever attempting them, once the bury ended. The problems
+// are new again, like after unsuspending them.
+func (b *Box) releaseBuried(user int64) (err error) {
+	query := `
+	DELETE FROM schedule WHERE user = ? AND suspended = 0 AND buried != 0 AND buried <= ? AND NOT EXISTS (
+		SELECT 1 FROM sessions WHERE sessions.problem = schedule.problem AND sessions.user = schedule.user
+	);
+	`
+	_, err = b.db.Exec(query, user, time.Now().Unix())
+	return
+}
+
+// Move the due date of each of the users schedules
+func (b *Box) shiftSchedule(user int64, shift func(due int64) int64) error {
+	tx, err := b.db.Begin()
+	if err != nil {
+		return err
+	}
+	rows, err := tx.Query(`SELECT id, due FROM schedule WHERE user = ?;`, user)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	dues := make(map[int64]int64)
+	for rows.Next() {
+		var id, due int64
+		if err := rows.Scan(&id, &due); err != nil {
+			rows.Close()
+			tx.Rollback()
+			return err
+		}
+		dues[id] = shift(due)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		tx.Rollback()
+		return err
+	}
+	query := `
+	UPDATE schedule SET due = ? WHERE id = ?;
+	`
+	for id, due := range dues {
+		if _, err := tx.Exec(query, due, id); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+func (b *Box) listLeeches(user, threshold int64) ([]Leech, error) {
+	query := `
+	SELECT ` + problemColumns + `, schedule.lapses, schedule.suspended FROM problems
+	INNER JOIN schedule ON schedule.problem = problems.id
+	WHERE schedule.user = ? AND schedule.lapses >= ? AND problems.archived = 0
+	ORDER BY schedule.lapses DESC;
+	`
+	leeches := []Leech{}
+	rows, err := b.db.Query(query, user, threshold)
+	if err != nil {
+		return leeches, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var l Leech
+		if l.Problem, err = scanProblem(rows, &l.Lapses, &l.Suspended); err != nil {
+			return leeches, err
+		}
+		leeches = append(leeches, l)
+	}
+	return leeches, rows.Err()
+}
+
+// Pick random problems for a mock interview, leaving
+// out the ones the user suspended. Problems can be
+// limited to a tag and a difficulty.
+func (b *Box) mockPool(user, count int64, tag string, difficulty Difficulty) ([]int64, error) {
+	query := `
+	SELECT id FROM problems WHERE archived = 0 AND NOT id IN (
+		SELECT problem FROM schedule WHERE user = ? AND suspended = 1
+	) AND (? = '' OR id IN (
+		SELECT problem_tags.problem FROM problem_tags INNER JOIN tags ON tags.id = problem_tags.tag WHERE tags.name = ?
+	)) AND (? = 0 OR difficulty = ?) ORDER BY RANDOM() LIMIT ?;
+	`
+	var pool []int64
+	rows, err := b.db.Query(query, user, tag, tag, difficulty, difficulty, count)
+	if err != nil {
+		return pool, err
+	}
+	defer rows.Close()
+	var id int64
+	for rows.Next() {
+		if err = rows.Scan(&id); err != nil {
+			return pool, err
+		}
+		pool = append(pool, id)
+	}
+	return pool, rows.Err()
+}
+
+func (b *Box) createMock(user int64, m Mock, problems []int64) (Mock, error) {
+	tx, err := b.db.Begin()
+	if err != nil {
+		return m, err
+	}
+	query := `
+	INSERT INTO mocks (user, started, deadline, count) VALUES (?, ?, ?, ?);
+	`
+	res, err := tx.Exec(query, user, m.Started, m.Deadline, m.Count)
+	if err != nil {
+		tx.Rollback()
+		return m, err
+	}
+	m.Id, _ = res.LastInsertId()
+	query = `
+	INSERT INTO mock_problems (mock, position, problem) VALUES (?, ?, ?);
+	`
+	for i, id := range problems {
+		if _, err := tx.Exec(query, m.Id, i, id); err != nil {
+			tx.Rollback()
+			return m, err
+		}
+	}
+	return m, tx.Commit()
+}
+
+func (b *Box) activeMock(user int64) (m Mock, err error) {
+	query := `
+	SELECT id, started, deadline, finished, count, score FROM mocks WHERE user = ? AND finished = 0 ORDER BY id DESC LIMIT 1;
 	`
 	row := b.db.QueryRow(query, user)
-	err = row.Scan(&p.Id, &p.Title, &p.Question, &p.Solution)
+	err = row.Scan(&m.Id, &m.Started, &m.Deadline, &m.Finished, &m.Count, &m.Score)
+	return
+}
+
+func (b *Box) getMock(id, user int64) (m Mock, err error) {
+	query := `
+	SELECT id, started, deadline, finished, count, score FROM mocks WHERE id = ? AND user = ?;
+	`
+	row := b.db.QueryRow(query, id, user)
+	err = row.Scan(&m.Id, &m.Started, &m.Deadline, &m.Finished, &m.Count, &m.Score)
+	return
+}
+
+func (b *Box) updateMock(m Mock) (err error) {
+	query := `
+	UPDATE mocks SET finished = ?, score = ? WHERE id = ?;
+	`
+	_, err = b.db.Exec(query, m.Finished, m.Score, m.Id)
+	return
+}
+
+func (b *Box) linkMockSession(mock, position, session int64) (err error) {
+	query := `
+	UPDATE mock_problems SET session = ? WHERE mock = ? AND position = ?;
+	`
+	_, err = b.db.Exec(query, session, mock, position)
+	return
+}
+
+func (b *Box) mockResults(mock int64) ([]MockResult, error) {
+	query := `
+	SELECT
+		mock_problems.position,
+		mock_problems.problem,
+		problems.title,
+		IFNULL(sessions.id, 0),
+		IFNULL(sessions.date, 0),
+		IFNULL(sessions.time, 0),
+		IFNULL(sessions.solved, 0),
+		IFNULL(sessions.grade, 0),
+		problems.archived != 0 AND sessions.id IS NULL
+	FROM mock_problems
+	INNER JOIN problems ON problems.id = mock_problems.problem
+	LEFT JOIN sessions ON sessions.id = mock_problems.session
+	WHERE mock_problems.mock = ?
+	ORDER BY mock_problems.position ASC;
+	`
+	var results []MockResult
+	rows, err := b.db.Query(query, mock)
+	if err != nil {
+		return results, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var r MockResult
+		err = rows.Scan(&r.Position, &r.Problem, &r.Title, &r.Session, &r.Date, &r.Time, &r.Solved, &r.Grade, &r.Skipped)
+		if err != nil {
+			return results, err
+		}
+		results = append(results, r)
+	}
+	return results, rows.Err()
+}
+
+// Columns read by scanSuggestion
+const suggestionColumns = `problem_suggestions.id, problem_suggestions.author, problem_suggestions.date, problem_suggestions.status,
+	problem_suggestions.problem, problem_suggestions.title, problem_suggestions.question, problem_suggestions.solution, problem_suggestions.starter,
+	problem_suggestions.target, problem_suggestions.difficulty, problem_suggestions.tags`
+
+func scanSuggestion(row scanner) (s Suggestion, err error) {
+	var tags string
+	p := &s.Problem
+	if err = row.Scan(&s.Id, &s.Author, &s.Date, &s.Status, &p.Id, &p.Title, &p.Question, &p.Solution, &p.Starter, &p.Target, &p.Difficulty, &tags); err == nil {
+		p.Tags, err = parseTags(tags)
+	}
+	return
+}
+
+func (b *Box) storeSuggestion(s Suggestion) (int64, error) {
+	query := `
+	INSERT INTO problem_suggestions (problem, author, date, status, title, question, solution, starter, target, difficulty, tags) VALUES (
+		?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?
+	);
+	`
+	p := s.Problem
+	res, err := b.db.Exec(query, p.Id, s.Author, s.Date, s.Status, p.Title, p.Question, p.Solution, p.Starter, p.Target, p.Difficulty, strings.Join(p.Tags, ","))
+	if err != nil {
+		return 0, err
+	}
+	return res.LastInsertId()
+}
+
+func (b *Box) getSuggestion(id int64) (Suggestion, error) {
+	query := `
+	SELECT ` + suggestionColumns + ` FROM problem_suggestions WHERE id = ?;
+	`
+	return scanSuggestion(b.db.QueryRow(query, id))
+}
+
+// List pending suggestions for problems owned by
+// the user, or for all problems
+func (b *Box) listSuggestions(user int64, all bool) ([]Suggestion, error) {
+	query := `
+	SELECT ` + suggestionColumns + ` FROM problem_suggestions
+	INNER JOIN problems ON problems.id = problem_suggestions.problem
+	WHERE problem_suggestions.status = ? AND (? OR problems.owner = ?)
+	ORDER BY problem_suggestions.id ASC;
+	`
+	list := []Suggestion{}
+	rows, err := b.db.Query(query, SuggestionPending, all, user)
+	if err != nil {
+		return list, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		s, err := scanSuggestion(rows)
+		if err != nil {
+			return list, err
+		}
+		list = append(list, s)
+	}
+	return list, rows.Err()
+}
+
+func (b *Box) setSuggestionStatus(id int64, status string) (err error) {
+	query := `
+	UPDATE problem_suggestions SET status = ? WHERE id = ?;
+	`
+	_, err = b.db.Exec(query, status, id)
+	return
+}
+
+func (b *Box) setArchived(id, archived int64) error {
+	query := `
+	UPDATE problems SET archived = ? WHERE id = ?;
+	`
+	if res, err := b.db.Exec(query, archived, id); err != nil {
+		return err
+	} else if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return ErrProblemNotExists
+	}
+	return nil
+}
+
+// Delete a problem and all rows which refer to it,
+// including the drafts of the draft package
+func (b *Box) purgeProblem(id int64) error {
+	tx, err := b.db.Begin()
+	if err != nil {
+		return err
+	}
+	query := `
+	DELETE FROM mock_problems WHERE problem = ?;
+	DELETE FROM session_results WHERE session IN (SELECT id FROM sessions WHERE problem = ?);
+	DELETE FROM sessions WHERE problem = ?;
+	DELETE FROM schedule WHERE problem = ?;
+	DELETE FROM drafts WHERE problem = ?;
+	DELETE FROM problem_tags WHERE problem = ?;
+	DELETE FROM problem_revisions WHERE problem = ?;
+	DELETE FROM problem_suggestions WHERE problem = ?;
+	DELETE FROM problem_tests WHERE problem = ?;
+	DELETE FROM problem_hints WHERE problem = ?;
+	DELETE FROM hint_usage WHERE problem = ?;
+	DELETE FROM problems WHERE id = ?;
+	`
+	if _, err := tx.Exec(query, id, id, id, id, id, id, id, id, id, id, id, id); err != nil {
+		tx.Rollback()
+		return err
+	}
+	if b.search {
+		if _, err := tx.Exec(`DELETE FROM problems_fts WHERE rowid = ?;`, id); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+func (b *Box) listTests(id int64) ([]TestCase, error) {
+	query := `
+	SELECT id, input, expected FROM problem_tests WHERE problem = ? AND retired = 0 ORDER BY position ASC;
+	`
+	tests := []TestCase{}
+	rows, err := b.db.Query(query, id)
+	if err != nil {
+		return tests, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var t TestCase
+		if err = rows.Scan(&t.Id, &t.Input, &t.Expected); err != nil {
+			return tests, err
+		}
+		tests = append(tests, t)
+	}
+	return tests, rows.Err()
+}
+
+// Replace the tests of a problem, the old tests are
+// retired rather than removed, as results of earlier
+// sessions refer to them. New tests are positioned
+// after all old ones.
+func (b *Box) setTests(id int64, tests []TestCase) error {
+	tx, err := b.db.Begin()
+	if err != nil {
+		return err
+	}
+	query := `
+	SELECT IFNULL(MAX(position) + 1, 0) FROM problem_tests WHERE problem = ?;
+	`
+	var next int64
+	if err := tx.QueryRow(query, id).Scan(&next); err != nil {
+		tx.Rollback()
+		return err
+	}
+	query = `
+	UPDATE problem_tests SET retired = 1 WHERE problem = ?;
+	`
+	if _, err := tx.Exec(query, id); err != nil {
+		tx.Rollback()
+		return err
+	}
+	query = `
+	INSERT INTO problem_tests (problem, position, input, expected) VALUES (?, ?, ?, ?);
+	`
+	for i, test := range tests {
+		if _, err := tx.Exec(query, id, next+int64(i), test.Input, test.Expected); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+func (b *Box) storeResults(session int64, results []TestResult) error {
+	query := `
+	INSERT INTO session_results (session, test, passed, output) VALUES (?, ?, ?, ?);
+	`
+	for _, res := range results {
+		if _, err := b.db.Exec(query, session, res.Test, res.Passed, res.Output); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (b *Box) listResults(session, user int64) ([]TestResult, error) {
+	query := `
+	SELECT test, passed, output FROM session_results WHERE session = (
+		SELECT id FROM sessions WHERE id = ? AND user = ?
+	) ORDER BY test ASC;
+	`
+	results := []TestResult{}
+	rows, err := b.db.Query(query, session, user)
+	if err != nil {
+		return results, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var res TestResult
+		if err = rows.Scan(&res.Test, &res.Passed, &res.Output); err != nil {
+			return results, err
+		}
+		results = append(results, res)
+	}
+	return results, rows.Err()
+}
+
+func (b *Box) listHints(id int64) ([]string, error) {
+	query := `
+	SELECT text FROM problem_hints WHERE problem = ? ORDER BY position ASC;
+	`
+	hints := []string{}
+	rows, err := b.db.Query(query, id)
+	if err != nil {
+		return hints, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var hint string
+		if err = rows.Scan(&hint); err != nil {
+			return hints, err
+		}
+		hints = append(hints, hint)
+	}
+	return hints, rows.Err()
+}
+
+func (b *Box) setHints(id int64, hints []string) error {
+	tx, err := b.db.Begin()
+	if err != nil {
+		return err
+	}
+	query := `
+	DELETE FROM problem_hints WHERE problem = ?;
+	`
+	if _, err := tx.Exec(query, id); err != nil {
+		tx.Rollback()
+		return err
+	}
+	query = `
+	INSERT INTO problem_hints (problem, position, text) VALUES (?, ?, ?);
+	`
+	for i, hint := range hints {
+		if _, err := tx.Exec(query, id, i, hint); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// Number of hints the user revealed since
+// the last submission of the problem
+func (b *Box) hintsUsed(id, user int64) (used int64, err error) {
+	query := `
+	SELECT IFNULL((SELECT used FROM hint_usage WHERE problem = ? AND user = ?), 0);
+	`
+	err = b.db.QueryRow(query, id, user).Scan(&used)
 	return
 }
+
+func (b *Box) useHint(id, user int64) (err error) {
+	query := `
+	INSERT INTO hint_usage (problem, user, used) VALUES (?, ?, 1)
+	ON CONFLICT (problem, user) DO UPDATE SET used = used + 1;
+	`
+	_, err = b.db.Exec(query, id, user)
+	return
+}
+
+func (b *Box) resetHints(id, user int64) (err error) {
+	query := `
+	DELETE FROM hint_usage WHERE problem = ? AND user = ?;
+	`
+	_, err = b.db.Exec(query, id, user)
+	return
+}
+
+// Replace the search index entry of a problem
+func (b *Box) indexProblem(p Problem) (err error) {
+	if !b.search {
+		return nil
+	}
+	query := `
+	DELETE FROM problems_fts WHERE rowid = ?;
+	INSERT INTO problems_fts (rowid, title, question, solution) VALUES (?, ?, ?, ?);
+	`
+	_, err = b.db.Exec(query, p.Id, p.Id, p.Title, p.Question, p.Solution)
+	return
+}
+
+// Search active problems, best matches first
+func (b *Box) searchProblems(match string, limit int64) ([]SearchResult, error) {
+	query := `
+	SELECT problems.id, problems.title, snippet(problems_fts, -1, ?, ?, '…', 16)
+	FROM problems_fts INNER JOIN problems ON problems.id = problems_fts.rowid
+	WHERE problems_fts MATCH ? AND problems.archived = 0
+	ORDER BY rank LIMIT ?;
+	`
+	results := []SearchResult{}
+	rows, err := b.db.Query(query, markStart, markEnd, match, limit)
+	if err != nil {
+		return results, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var res SearchResult
+		if err = rows.Scan(&res.Problem, &res.Title, &res.Snippet); err != nil {
+			return results, err
+		}
+		res.Snippet = highlight(res.Snippet)
+		results = append(results, res)
+	}
+	return results, rows.Err()
+}
+
+// Search the code of the users sessions for all terms,
+// latest first. The snippet is set to the whole code.
+func (b *Box) searchSessions(user int64, terms []string, limit int64) ([]SearchResult, error) {
+	query := `
+	SELECT sessions.problem, problems.title, sessions.id, sessions.code
+	FROM sessions INNER JOIN problems ON problems.id = sessions.problem
+	WHERE sessions.user = ?`
+	args := []interface{}{user}
+	for _, term := range terms {
+		query += ` AND instr(lower(sessions.code), lower(?)) > 0`
+		args = append(args, term)
+	}
+	query += `
+	ORDER BY sessions.date DESC LIMIT ?;
+	`
+	results := []SearchResult{}
+	rows, err := b.db.Query(query, append(args, limit)...)
+	if err != nil {
+		return results, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var res SearchResult
+		if err = rows.Scan(&res.Problem, &res.Title, &res.Session, &res.Snippet); err != nil {
+			return results, err
+		}
+		results = append(results, res)
+	}
+	return results, rows.Err()
+}
+
+// Load problems following the problem with id after and
+// sort key, or the first problems if after is 0
+func (b *Box) listProblems(user int64, tag string, s listSort, after int64, key interface{}, limit int64) ([]ListedProblem, error) {
+	dir, cmp := "ASC", ">"
+	if s.desc {
+		dir, cmp = "DESC", "<"
+	}
+	query := `
+	SELECT ` + problemColumns + `, IFNULL(attempts.date, 0), schedule.problem IS NOT NULL,
+		IFNULL(schedule.due, 0), IFNULL(schedule.suspended, 0), IFNULL(schedule.buried, 0)
+	FROM problems
+	LEFT JOIN schedule ON schedule.problem = problems.id AND schedule.user = ?
+	LEFT JOIN (
+		SELECT problem, MAX(date) AS date FROM sessions WHERE user = ? GROUP BY problem
+	) AS attempts ON attempts.problem = problems.id
+	WHERE problems.archived = 0 AND (? = '' OR problems.id IN (
+		SELECT problem_tags.problem FROM problem_tags INNER JOIN tags ON tags.id = problem_tags.tag WHERE tags.name = ?
+	))`
+	args := []interface{}{user, user, tag, tag}
+	if after != 0 {
+		query += ` AND (` + s.expr + ` ` + cmp + ` ? OR (` + s.expr + ` = ? AND problems.id ` + cmp + ` ?))`
+		args = append(args, key, key, after)
+	}
+	query += `
+	ORDER BY ` + s.expr + ` ` + dir + `, problems.id ` + dir + ` LIMIT ?;
+	`
+	problems := []ListedProblem{}
+	rows, err := b.db.Query(query, append(args, limit)...)
+	if err != nil {
+		return problems, err
+	}
+	defer rows.Close()
+	now := time.Now().Unix()
+	for rows.Next() {
+		var l ListedProblem
+		var scheduled, suspended bool
+		var buried int64
+		if l.Problem, err = scanProblem(rows, &l.Attempted, &scheduled, &l.Due, &suspended, &buried); err != nil {
+			return problems, err
+		}
+		listStatus(&l, scheduled, suspended, buried, now)
+		problems = append(problems, l)
+	}
+	return problems, rows.Err()
+}
+
+// Titles and questions of the active problems
+func (b *Box) problemTexts() ([]Problem, error) {
+	query := `
+	SELECT id, title, question FROM problems WHERE archived = 0;
+	`
+	var problems []Problem
+	rows, err := b.db.Query(query)
+	if err != nil {
+		return problems, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var p Problem
+		if err = rows.Scan(&p.Id, &p.Title, &p.Question); err != nil {
+			return problems, err
+		}
+		problems = append(problems, p)
+	}
+	return problems, rows.Err()
+}
@@ -0,0 +1,62 @@
+// Line based diff of two texts, using the
+// longest common subsequence of their lines
+
+package problem
+
+import (
+	"strings"
+)
+
+const (
+	DiffSame   = " "
+	DiffInsert = "+"
+	DiffDelete = "-"
+)
+
+type DiffLine struct {
+	Op   string `json:"op"`
+	Text string `json:"text"`
+}
+
+func diffLines(a, b string) []DiffLine {
+	x, y := strings.Split(a, "\n"), strings.Split(b, "\n")
+	// lcs[i][j] is the common subsequence length of x[i:] and y[j:]
+	lcs := make([][]int, len(x)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(y)+1)
+	}
+	for i := len(x) - 1; i >= 0; i-- {
+		for j := len(y) - 1; j >= 0; j-- {
+			if x[i] == y[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+	diff := []DiffLine{}
+	i, j := 0, 0
+	for i < len(x) && j < len(y) {
+		switch {
+		case x[i] == y[j]:
+			diff = append(diff, DiffLine{DiffSame, x[i]})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			diff = append(diff, DiffLine{DiffDelete, x[i]})
+			i++
+		default:
+			diff = append(diff, DiffLine{DiffInsert, y[j]})
+			j++
+		}
+	}
+	for ; i < len(x); i++ {
+		diff = append(diff, DiffLine{DiffDelete, x[i]})
+	}
+	for ; j < len(y); j++ {
+		diff = append(diff, DiffLine{DiffInsert, y[j]})
+	}
+	return diff
+}
@@ -0,0 +1,74 @@
+// Problem difficulty, used to ramp up new
+// problems as users get better
+
+package problem
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+)
+
+var (
+	ErrDifficulty = errors.New("Difficulty must be easy, medium or hard")
+)
+
+type Difficulty int
+
+const (
+	DifficultyUnknown Difficulty = iota
+	DifficultyEasy
+	DifficultyMedium
+	DifficultyHard
+)
+
+var difficultyNames = []string{"", "easy", "medium", "hard"}
+
+const (
+	rampSessions = 10  // Number of recent sessions used to ramp difficulty
+	rampMedium   = 0.5 // Success rate from which medium problems are served
+	rampHard     = 0.8 // Success rate from which hard problems are served
+)
+
+// Parse a difficulty from its name or number,
+// an empty string is an unknown difficulty
+func ParseDifficulty(str string) (Difficulty, error) {
+	str = strings.ToLower(strings.Trim(str, " "))
+	for i, name := range difficultyNames {
+		if name == str {
+			return Difficulty(i), nil
+		}
+	}
+	if n, err := strconv.Atoi(str); err == nil && n >= int(DifficultyUnknown) && n <= int(DifficultyHard) {
+		return Difficulty(n), nil
+	}
+	return 0, ErrDifficulty
+}
+
+func (d Difficulty) String() string {
+	if d < DifficultyUnknown || d > DifficultyHard {
+		return "difficulty(" + strconv.Itoa(int(d)) + ")"
+	}
+	return difficultyNames[d]
+}
+
+func (d Difficulty) MarshalText() ([]byte, error) {
+	return []byte(d.String()), nil
+}
+
+// Hardest difficulty to serve new problems at,
+// given the users recent sessions
+func rampDifficulty(sessions, solved int64) Difficulty {
+	if sessions == 0 {
+		return DifficultyEasy
+	}
+	rate := float64(solved) / float64(sessions)
+	switch {
+	case rate >= rampHard:
+		return DifficultyHard
+	case rate >= rampMedium:
+		return DifficultyMedium
+	default:
+		return DifficultyEasy
+	}
+}
@@ -0,0 +1,91 @@
+// Detect problems which are created twice, by
+// comparing the words of titles and questions
+
+package problem
+
+import (
+	"errors"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+var (
+	ErrDuplicate = errors.New("Similar problems exist, create with force to add it anyway")
+)
+
+const (
+	duplicateScore = 0.6 // Problems scoring at least this are likely duplicates
+	maxDuplicates  = 5
+	titleWeight    = 0.3 // Weight of the title when combined with the question
+	titleScore     = 0.9 // Titles scoring at least this are duplicates on their own
+)
+
+type Duplicate struct {
+	Problem int64   `json:"problem"`
+	Title   string  `json:"title"`
+	Score   float64 `json:"score"` // Similarity from 0 to 1
+}
+
+// Lower case words of a text, ignoring punctuation
+// and single characters
+func normalizedWords(str string) map[string]bool {
+	words := make(map[string]bool)
+	for _, w := range strings.FieldsFunc(strings.ToLower(str), func(c rune) bool {
+		return !unicode.IsLetter(c) && !unicode.IsDigit(c)
+	}) {
+		if len([]rune(w)) > 1 {
+			words[w] = true
+		}
+	}
+	return words
+}
+
+// Jaccard similarity of two word sets
+func similarity(a, b map[string]bool) float64 {
+	if len(a) == 0 || len(b) == 0 {
+		return 0
+	}
+	var common int
+	for w := range a {
+		if b[w] {
+			common++
+		}
+	}
+	return float64(common) / float64(len(a)+len(b)-common)
+}
+
+// Score how alike two problems are, a (nearly) equal
+// title is enough, otherwise the question has to match
+// too, so "Binary Search" and "Binary Search Tree" are
+// only duplicates if they ask the same
+func problemSimilarity(p, q Problem) float64 {
+	title := similarity(normalizedWords(p.Title), normalizedWords(q.Title))
+	question := similarity(normalizedWords(p.Question), normalizedWords(q.Question))
+	combined := titleWeight*title + (1-titleWeight)*question
+	if title >= titleScore && title > combined {
+		return title
+	}
+	return combined
+}
+
+// Find the active problems most similar to p
+func (b *Box) findDuplicates(p Problem) ([]Duplicate, error) {
+	problems, err := b.problemTexts()
+	if err != nil {
+		return nil, err
+	}
+	var duplicates []Duplicate
+	for _, q := range problems {
+		if score := problemSimilarity(p, q); score >= duplicateScore {
+			duplicates = append(duplicates, Duplicate{q.Id, q.Title, score})
+		}
+	}
+	sort.Slice(duplicates, func(i, j int) bool {
+		return duplicates[i].Score > duplicates[j].Score
+	})
+	if len(duplicates) > maxDuplicates {
+		duplicates = duplicates[:maxDuplicates]
+	}
+	return duplicates, nil
+}
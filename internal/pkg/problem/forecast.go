@@ -0,0 +1,68 @@
+// Forecast of how many problems come
+// due on the following days
+
+package problem
+
+import (
+	"errors"
+	"net/http"
+	"time"
+)
+
+var (
+	ErrForecastDays = errors.New("Forecast must span between 1 and 365 days")
+)
+
+const defaultForecastDays = 30
+
+type Forecast struct {
+	Overdue int64         `json:"overdue"` // Problems due before today
+	Days    []ForecastDay `json:"days"`    // Starting with today
+}
+
+type ForecastDay struct {
+	Date string `json:"date"` // Local date of the user, as YYYY-MM-DD
+	Due  int64  `json:"due"`  // Problems due on that day
+}
+
+// Implement server api functions
+
+func (b *Box) ScheduleForecast(r *http.Request, user int64) (interface{}, error) {
+	days, err := formInt(r, "days", defaultForecastDays)
+	if err != nil {
+		return nil, err
+	} else if days < 1 || days > 365 {
+		return nil, ErrForecastDays
+	}
+	settings, err := b.getSettings(user)
+	if err != nil {
+		return nil, err
+	} else if err := b.releaseBuried(user); err != nil {
+		return nil, err
+	}
+	today := settings.startOfDay(time.Now())
+	end := today.AddDate(0, 0, int(days))
+	dues, err := b.dueDates(user, end.Unix())
+	if err != nil {
+		return nil, err
+	}
+	var forecast Forecast
+	forecast.Days = make([]ForecastDay, days)
+	for i := range forecast.Days {
+		forecast.Days[i].Date = today.AddDate(0, 0, i).Format("2006-01-02")
+	}
+	for _, due := range dues {
+		if due < today.Unix() {
+			forecast.Overdue++
+			continue
+		}
+		// Days are not always 24 hours long
+		for i := len(forecast.Days) - 1; i >= 0; i-- {
+			if due >= today.AddDate(0, 0, i).Unix() {
+				forecast.Days[i].Due++
+				break
+			}
+		}
+	}
+	return forecast, nil
+}
@@ -0,0 +1,62 @@
+// Self-assessed grades for a session,
+// from forgotten to trivial
+
+package problem
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+)
+
+var (
+	ErrGrade = errors.New("Grade must be again, hard, good or easy")
+)
+
+type Grade int
+
+const (
+	GradeAgain Grade = iota + 1 // Not solved
+	GradeHard                   // Solved with serious difficulty
+	GradeGood                   // Solved after some thought
+	GradeEasy                   // Solved without effort
+)
+
+var gradeNames = []string{"", "again", "hard", "good", "easy"}
+
+// Parse a grade from its name or number
+func ParseGrade(str string) (Grade, error) {
+	str = strings.ToLower(strings.Trim(str, " "))
+	for i, name := range gradeNames {
+		if i > 0 && name == str {
+			return Grade(i), nil
+		}
+	}
+	if n, err := strconv.Atoi(str); err == nil && n >= int(GradeAgain) && n <= int(GradeEasy) {
+		return Grade(n), nil
+	}
+	return 0, ErrGrade
+}
+
+// Map the legacy solved form value onto a grade
+func legacyGrade(solved string) Grade {
+	if solved == "0" {
+		return GradeAgain
+	}
+	return GradeGood
+}
+
+func (g Grade) Solved() bool {
+	return g > GradeAgain
+}
+
+func (g Grade) String() string {
+	if g < GradeAgain || g > GradeEasy {
+		return "grade(" + strconv.Itoa(int(g)) + ")"
+	}
+	return gradeNames[g]
+}
+
+func (g Grade) MarshalText() ([]byte, error) {
+	return []byte(g.String()), nil
+}
@@ -0,0 +1,98 @@
+// Problems can carry an ordered list of hints,
+// which users reveal one at a time. Hinted
+// solves are scheduled like weaker solves.
+
+package problem
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+var (
+	ErrHints   = errors.New("Hints must be a list of non-empty texts")
+	ErrNoHints = errors.New("No more hints for this problem")
+)
+
+// Hints revealed to a user for a problem
+type Hints struct {
+	Hints []string `json:"hints"` // Revealed hints, in order
+	Total int64    `json:"total"` // Number of hints of the problem
+}
+
+// Grade the scheduler sees for a session, solves
+// which needed hints count one grade lower, but
+// still as solved
+func (s Session) effectiveGrade() Grade {
+	if s.Hints > 0 && s.Grade > GradeHard {
+		return s.Grade - 1
+	}
+	return s.Grade
+}
+
+func (b *Box) revealedHints(id, user int64) (h Hints, err error) {
+	hints, err := b.listHints(id)
+	if err != nil {
+		return
+	}
+	used, err := b.hintsUsed(id, user)
+	if err != nil {
+		return
+	}
+	if used > int64(len(hints)) {
+		used = int64(len(hints))
+	}
+	return Hints{hints[:used], int64(len(hints))}, nil
+}
+
+// Implement server api functions
+
+func (b *Box) ProblemHints(r *http.Request, user int64) (interface{}, error) {
+	// List the hints the user revealed for the current attempt
+	if id, err := strconv.ParseInt(r.FormValue("id"), 10, 64); err != nil {
+		return nil, err
+	} else {
+		return b.revealedHints(id, user)
+	}
+}
+
+func (b *Box) ProblemHint(r *http.Request, user int64) (interface{}, error) {
+	// Reveal the next hint, which is recorded
+	// with the next submitted session
+	id, err := strconv.ParseInt(r.FormValue("id"), 10, 64)
+	if err != nil {
+		return nil, err
+	}
+	h, err := b.revealedHints(id, user)
+	if err != nil {
+		return nil, err
+	} else if int64(len(h.Hints)) >= h.Total {
+		return h, ErrNoHints
+	}
+	if err := b.useHint(id, user); err != nil {
+		return nil, err
+	}
+	return b.revealedHints(id, user)
+}
+
+func (b *Box) ProblemHintsUpdate(r *http.Request, user int64) (interface{}, error) {
+	// Replace the hints of a problem, given
+	// as a JSON list of texts
+	p, err := b.ownProblem(r, user)
+	if err != nil {
+		return nil, err
+	}
+	var hints []string
+	if err := json.Unmarshal([]byte(r.FormValue("hints")), &hints); err != nil {
+		return nil, ErrHints
+	}
+	for i, hint := range hints {
+		if hints[i] = strings.Trim(hint, " \n"); hints[i] == "" {
+			return nil, ErrHints
+		}
+	}
+	return p.Id, b.setHints(p.Id, hints)
+}
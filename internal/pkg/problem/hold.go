@@ -0,0 +1,84 @@
+// Users can take problems out of their
+// rotation, either for good or until the
+// next day
+
+package problem
+
+import (
+	"database/sql"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Load the schedule of a problem, problems the user
+// did not attempt get a schedule which is due now
+func (b *Box) holdSchedule(id, user int64) (Schedule, error) {
+	sched, err := b.getSchedule(id, user)
+	if err == sql.ErrNoRows {
+		if _, err := b.getProblem(id); err == sql.ErrNoRows {
+			return sched, ErrProblemNotExists
+		} else if err != nil {
+			return sched, err
+		}
+		sched = newSchedule(id, user)
+		sched.Due = time.Now().Unix()
+		return sched, nil
+	}
+	return sched, err
+}
+
+// Implement server api functions
+
+func (b *Box) ProblemSuspend(r *http.Request, user int64) (interface{}, error) {
+	// Remove problem from the users rotation
+	id, err := strconv.ParseInt(r.FormValue("id"), 10, 64)
+	if err != nil {
+		return nil, err
+	}
+	sched, err := b.holdSchedule(id, user)
+	if err != nil {
+		return nil, err
+	}
+	sched.Suspended = true
+	return nil, b.scheduleProblem(sched)
+}
+
+func (b *Box) ProblemUnsuspend(r *http.Request, user int64) (interface{}, error) {
+	// Return problem to the users rotation
+	id, err := strconv.ParseInt(r.FormValue("id"), 10, 64)
+	if err != nil {
+		return nil, err
+	}
+	sched, err := b.holdSchedule(id, user)
+	if err != nil {
+		return nil, err
+	}
+	history, err := b.sessionHistory(id, user)
+	if err != nil {
+		return nil, err
+	} else if len(history) == 0 && sched.Buried <= time.Now().Unix() {
+		// Problem was never attempted, so it is new again
+		return nil, b.unscheduleProblem(id, user)
+	}
+	sched.Suspended = false
+	return nil, b.scheduleProblem(sched)
+}
+
+func (b *Box) ProblemBury(r *http.Request, user int64) (interface{}, error) {
+	// Skip problem until the users next day
+	id, err := strconv.ParseInt(r.FormValue("id"), 10, 64)
+	if err != nil {
+		return nil, err
+	}
+	settings, err := b.getSettings(user)
+	if err != nil {
+		return nil, err
+	}
+	sched, err := b.holdSchedule(id, user)
+	if err != nil {
+		return nil, err
+	}
+	sched.Buried = settings.startOfDay(time.Now()).AddDate(0, 0, 1).Unix()
+	return nil, b.scheduleProblem(sched)
+}
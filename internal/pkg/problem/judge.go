@@ -0,0 +1,103 @@
+// Problems can carry test cases, submissions
+// are then judged against them
+
+package problem
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+)
+
+var (
+	ErrTests = errors.New("Tests must be a list of at most 20 inputs and expected outputs")
+)
+
+const maxTests = 20 // Tests per problem, judging runs all of them
+
+type TestCase struct {
+	Id       int64  `json:"id"`
+	Input    string `json:"input"`    // JavaScript expression calling the submission
+	Expected string `json:"expected"` // JavaScript expression of the expected result
+}
+
+type TestResult struct {
+	Test   int64  `json:"test"`
+	Passed bool   `json:"passed"`
+	Output string `json:"output"` // Result or error of the submission
+}
+
+type Judge interface {
+	// Runs submitted code against test cases and
+	// returns one result per test case, in order
+	Run(code string, tests []TestCase) ([]TestResult, error)
+}
+
+// Run the judge on a session, if there is one and the
+// problem has tests. A session is solved if all tests
+// pass, which overrides the self-assessed grade.
+func (b *Box) judge(sess Session) (Session, []TestResult, error) {
+	if b.Judge == nil {
+		return sess, nil, nil
+	}
+	tests, err := b.listTests(sess.Problem)
+	if err != nil || len(tests) == 0 {
+		return sess, nil, err
+	}
+	results, err := b.Judge.Run(sess.Code, tests)
+	if err != nil {
+		return sess, nil, err
+	}
+	passed := true
+	for _, res := range results {
+		passed = passed && res.Passed
+	}
+	if !passed {
+		sess.Grade = GradeAgain
+	} else if !sess.Grade.Solved() {
+		sess.Grade = GradeGood
+	}
+	return sess, results, nil
+}
+
+// Implement server api functions
+
+func (b *Box) ProblemTests(r *http.Request, user int64) (interface{}, error) {
+	// List the test cases of a problem
+	if id, err := strconv.ParseInt(r.FormValue("id"), 10, 64); err != nil {
+		return nil, err
+	} else {
+		return b.listTests(id)
+	}
+}
+
+func (b *Box) ProblemTestsUpdate(r *http.Request, user int64) (interface{}, error) {
+	// Replace the test cases of a problem, given
+	// as a JSON list
+	p, err := b.ownProblem(r, user)
+	if err != nil {
+		return nil, err
+	}
+	var tests []TestCase
+	if err := json.Unmarshal([]byte(r.FormValue("tests")), &tests); err != nil {
+		return nil, ErrTests
+	} else if len(tests) > maxTests {
+		return nil, ErrTests
+	}
+	for _, test := range tests {
+		if test.Input == "" || test.Expected == "" {
+			return nil, ErrTests
+		}
+	}
+	return p.Id, b.setTests(p.Id, tests)
+}
+
+func (b *Box) SessionResults(r *http.Request, user int64) (interface{}, error) {
+	// List the test results of one of the users sessions
+	if id, err := strconv.ParseInt(r.FormValue("id"), 10, 64); err != nil {
+		return nil, err
+	} else {
+		return b.listResults(id, user)
+	}
+}
@@ -0,0 +1,38 @@
+// Leeches are problems a user keeps failing,
+// they are either kept or suspended
+
+package problem
+
+import (
+	"net/http"
+)
+
+const (
+	LeechKeep    = "keep"    // Keep serving leeches
+	LeechSuspend = "suspend" // Remove leeches from the users rotation
+
+	DefaultLeechThreshold = 8
+)
+
+type Leech struct {
+	Problem   Problem `json:"problem"`
+	Lapses    int64   `json:"lapses"`
+	Suspended bool    `json:"suspended"`
+}
+
+// Count a failed session against the schedule and
+// apply the leech policy once the threshold is hit
+func (b *Box) recordLapse(s Schedule) Schedule {
+	s.Lapses++
+	if s.Lapses >= b.LeechThreshold && b.LeechPolicy == LeechSuspend {
+		s.Suspended = true
+	}
+	return s
+}
+
+// Implement server api functions
+
+func (b *Box) ProblemLeeches(r *http.Request, user int64) (interface{}, error) {
+	// List the users leeches, most lapses first
+	return b.listLeeches(user, b.LeechThreshold)
+}
@@ -0,0 +1,157 @@
+// Browse all active problems page by page,
+// with the status of each problem for the user
+
+package problem
+
+import (
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+var (
+	ErrSort     = errors.New("Sort must be created, title, difficulty or attempted")
+	ErrCursor   = errors.New("Invalid cursor")
+	ErrPageSize = errors.New("Limit must be between 1 and 100")
+)
+
+const defaultPageSize = 20
+
+// Status of a problem for a user
+const (
+	ListNew       = "new"       // Never scheduled for the user
+	ListDue       = "due"       // Served by ProblemNext
+	ListScheduled = "scheduled" // Due at a later date
+	ListSuspended = "suspended"
+)
+
+type ListedProblem struct {
+	Problem   Problem `json:"problem"`
+	Status    string  `json:"status"`
+	Due       int64   `json:"due"`       // Unix time the problem is due, 0 if new or suspended
+	Attempted int64   `json:"attempted"` // Unix time of the last session, 0 if never attempted
+}
+
+type ProblemPage struct {
+	Problems []ListedProblem `json:"problems"`
+	Cursor   string          `json:"cursor"` // Pass to get the next page, empty on the last page
+}
+
+type listSort struct {
+	expr string // Column expression sorted by, ties are sorted by id
+	desc bool
+	text bool                              // Set if the expression is text
+	key  func(p ListedProblem) interface{} // Value of the expression for a problem
+}
+
+// Orders in which problems can be listed,
+// by default the newest problems come first
+var listSorts = map[string]listSort{
+	"created": {"problems.id", true, false, func(p ListedProblem) interface{} {
+		return p.Problem.Id
+	}},
+	"title": {"problems.title", false, true, func(p ListedProblem) interface{} {
+		return p.Problem.Title
+	}},
+	"difficulty": {"problems.difficulty", false, false, func(p ListedProblem) interface{} {
+		return int64(p.Problem.Difficulty)
+	}},
+	"attempted": {"IFNULL(attempts.date, 0)", true, false, func(p ListedProblem) interface{} {
+		return p.Attempted
+	}},
+}
+
+// Set the status of a problem given its schedule
+func listStatus(p *ListedProblem, scheduled, suspended bool, buried int64, now int64) {
+	switch {
+	case !scheduled:
+		p.Status, p.Due = ListNew, 0
+	case suspended:
+		p.Status, p.Due = ListSuspended, 0
+	default:
+		if buried > p.Due {
+			p.Due = buried
+		}
+		p.Status = ListDue
+		if p.Due > now {
+			p.Status = ListScheduled
+		}
+	}
+}
+
+// Cursors hold the sort key and id of
+// the last problem of a page
+func encodeCursor(s listSort, p ListedProblem) string {
+	str := strconv.FormatInt(p.Problem.Id, 10) + ":"
+	switch key := s.key(p).(type) {
+	case string:
+		str += key
+	case int64:
+		str += strconv.FormatInt(key, 10)
+	}
+	return base64.RawURLEncoding.EncodeToString([]byte(str))
+}
+
+func decodeCursor(s listSort, cursor string) (id int64, key interface{}, err error) {
+	str, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return 0, nil, ErrCursor
+	}
+	parts := strings.SplitN(string(str), ":", 2)
+	if len(parts) != 2 {
+		return 0, nil, ErrCursor
+	}
+	if id, err = strconv.ParseInt(parts[0], 10, 64); err != nil {
+		return 0, nil, ErrCursor
+	}
+	if s.text {
+		return id, parts[1], nil
+	}
+	if key, err = strconv.ParseInt(parts[1], 10, 64); err != nil {
+		return 0, nil, ErrCursor
+	}
+	return id, key, nil
+}
+
+// Implement server api functions
+
+func (b *Box) ProblemList(r *http.Request, user int64) (interface{}, error) {
+	// List a page of problems, optionally with a given tag,
+	// sorted by created, title, difficulty or attempted
+	name := r.FormValue("sort")
+	if name == "" {
+		name = "created"
+	}
+	s, ok := listSorts[name]
+	if !ok {
+		return nil, ErrSort
+	}
+	limit, err := formInt(r, "limit", defaultPageSize)
+	if err != nil {
+		return nil, err
+	} else if limit < 1 || limit > 100 {
+		return nil, ErrPageSize
+	}
+	var after int64
+	var key interface{}
+	if cursor := r.FormValue("cursor"); cursor != "" {
+		if after, key, err = decodeCursor(s, cursor); err != nil {
+			return nil, err
+		}
+	}
+	if err := b.releaseBuried(user); err != nil {
+		return nil, err
+	}
+	problems, err := b.listProblems(user, formTag(r), s, after, key, limit+1)
+	if err != nil {
+		return nil, err
+	}
+	page := ProblemPage{Problems: problems}
+	if int64(len(problems)) > limit {
+		page.Problems = problems[:limit]
+		page.Cursor = encodeCursor(s, page.Problems[limit-1])
+	}
+	return page, nil
+}
@@ -0,0 +1,210 @@
+// Render the Markdown of questions and solutions
+// to HTML. Raw HTML in the source is escaped, so
+// only the tags below are ever produced:
+// p, br, h1-h6, pre, code, blockquote, ul, ol,
+// li, hr, strong, em and a with a safe URL.
+
+package problem
+
+import (
+	"html"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	headingLine  = regexp.MustCompile(`^(#{1,6})\s+(.*?)\s*#*\s*$`)
+	fenceLine    = regexp.MustCompile("^\\s{0,3}(```+|~~~+)\\s*([A-Za-z0-9_+-]*)")
+	ruleLine     = regexp.MustCompile(`^\s{0,3}([-*_])(\s*([-*_])){2,}\s*$`)
+	bulletItem   = regexp.MustCompile(`^\s{0,3}[-*+]\s+(.*)$`)
+	orderedItem  = regexp.MustCompile(`^\s{0,3}\d{1,9}[.)]\s+(.*)$`)
+	quoteLine    = regexp.MustCompile(`^\s{0,3}>\s?(.*)$`)
+	safeSchemes  = map[string]bool{"": true, "http": true, "https": true, "mailto": true}
+	markdownPunc = "\\`*_{}[]()#+-.!>~|"
+)
+
+// Render Markdown source to sanitized HTML
+func renderMarkdown(src string) string {
+	lines := strings.Split(strings.Replace(src, "\r\n", "\n", -1), "\n")
+	var out strings.Builder
+	renderBlocks(&out, lines)
+	return out.String()
+}
+
+func renderBlocks(out *strings.Builder, lines []string) {
+	for i := 0; i < len(lines); {
+		line := lines[i]
+		switch {
+		case strings.TrimSpace(line) == "":
+			i++
+		case fenceLine.MatchString(line):
+			// Fenced code runs to the closing fence
+			// or the end of the source
+			m := fenceLine.FindStringSubmatch(line)
+			var code []string
+			for i++; i < len(lines); i++ {
+				if strings.HasPrefix(strings.TrimSpace(lines[i]), m[1]) {
+					i++
+					break
+				}
+				code = append(code, lines[i])
+			}
+			out.WriteString("<pre><code")
+			if m[2] != "" {
+				out.WriteString(` class="language-` + m[2] + `"`)
+			}
+			out.WriteString(">" + html.EscapeString(strings.Join(code, "\n")) + "</code></pre>\n")
+		case headingLine.MatchString(line):
+			m := headingLine.FindStringSubmatch(line)
+			level := strconv.Itoa(len(m[1]))
+			out.WriteString("<h" + level + ">" + renderInline(m[2]) + "</h" + level + ">\n")
+			i++
+		case ruleLine.MatchString(line):
+			out.WriteString("<hr>\n")
+			i++
+		case quoteLine.MatchString(line):
+			var quote []string
+			for ; i < len(lines) && quoteLine.MatchString(lines[i]); i++ {
+				quote = append(quote, quoteLine.FindStringSubmatch(lines[i])[1])
+			}
+			out.WriteString("<blockquote>\n")
+			renderBlocks(out, quote)
+			out.WriteString("</blockquote>\n")
+		case bulletItem.MatchString(line):
+			i = renderList(out, lines, i, bulletItem, "ul")
+		case orderedItem.MatchString(line):
+			i = renderList(out, lines, i, orderedItem, "ol")
+		default:
+			// Paragraphs keep their line breaks
+			var para []string
+			for ; i < len(lines) && !startsBlock(lines[i]); i++ {
+				para = append(para, renderInline(strings.TrimSpace(lines[i])))
+			}
+			out.WriteString("<p>" + strings.Join(para, "<br>\n") + "</p>\n")
+		}
+	}
+}
+
+// Render the list starting at line i, indented
+// lines continue the previous item. Returns the
+// line following the list.
+func renderList(out *strings.Builder, lines []string, i int, item *regexp.Regexp, tag string) int {
+	var items []string
+	for ; i < len(lines); i++ {
+		if m := item.FindStringSubmatch(lines[i]); m != nil {
+			items = append(items, renderInline(m[1]))
+		} else if strings.HasPrefix(lines[i], " ") && strings.TrimSpace(lines[i]) != "" {
+			items[len(items)-1] += "<br>\n" + renderInline(strings.TrimSpace(lines[i]))
+		} else {
+			break
+		}
+	}
+	out.WriteString("<" + tag + ">\n")
+	for _, it := range items {
+		out.WriteString("<li>" + it + "</li>\n")
+	}
+	out.WriteString("</" + tag + ">\n")
+	return i
+}
+
+func startsBlock(line string) bool {
+	return strings.TrimSpace(line) == "" ||
+		fenceLine.MatchString(line) ||
+		headingLine.MatchString(line) ||
+		ruleLine.MatchString(line) ||
+		quoteLine.MatchString(line) ||
+		bulletItem.MatchString(line) ||
+		orderedItem.MatchString(line)
+}
+
+// Render code spans, links, strong and emphasised
+// text, everything else is escaped
+func renderInline(s string) string {
+	var out strings.Builder
+	for i := 0; i < len(s); {
+		// Copy plain text up to the next special character
+		if j := strings.IndexAny(s[i:], "\\`[*_"); j != 0 {
+			if j < 0 {
+				j = len(s) - i
+			}
+			out.WriteString(html.EscapeString(s[i : i+j]))
+			i += j
+			continue
+		}
+		switch c := s[i]; c {
+		case '\\':
+			if i+1 < len(s) && strings.IndexByte(markdownPunc, s[i+1]) >= 0 {
+				out.WriteString(html.EscapeString(s[i+1 : i+2]))
+				i += 2
+				continue
+			}
+		case '`':
+			if j := strings.IndexByte(s[i+1:], '`'); j >= 0 {
+				out.WriteString("<code>" + html.EscapeString(s[i+1:i+1+j]) + "</code>")
+				i += j + 2
+				continue
+			}
+		case '[':
+			if text, href, n := parseLink(s[i:]); n > 0 {
+				if safeURL(href) {
+					out.WriteString(`<a href="` + html.EscapeString(href) + `" rel="nofollow noopener" target="_blank">` + renderInline(text) + "</a>")
+				} else {
+					out.WriteString(renderInline(text))
+				}
+				i += n
+				continue
+			}
+		case '*', '_':
+			n := 1
+			if i+1 < len(s) && s[i+1] == c {
+				n = 2
+			}
+			delim := s[i : i+n]
+			// Underscores inside words, like snake_case, are text
+			intraword := c == '_' && i > 0 && isWordByte(s[i-1])
+			if j := strings.Index(s[i+n:], delim); !intraword && j > 0 && s[i+n] != ' ' && s[i+n+j-1] != ' ' {
+				tag := "em"
+				if n == 2 {
+					tag = "strong"
+				}
+				out.WriteString("<" + tag + ">" + renderInline(s[i+n:i+n+j]) + "</" + tag + ">")
+				i += n + j + n
+				continue
+			}
+			out.WriteString(delim)
+			i += n
+			continue
+		}
+		out.WriteString(html.EscapeString(s[i : i+1]))
+		i++
+	}
+	return out.String()
+}
+
+// Parse a link of the form [text](url) at the start
+// of s, returns the length of the link or 0 if s
+// does not start with a link
+func parseLink(s string) (text, href string, n int) {
+	end := strings.IndexByte(s, ']')
+	if end < 0 || end+1 >= len(s) || s[end+1] != '(' {
+		return "", "", 0
+	}
+	close := strings.IndexByte(s[end:], ')')
+	if close < 0 {
+		return "", "", 0
+	}
+	return s[1:end], strings.TrimSpace(s[end+2 : end+close]), end + close + 1
+}
+
+// Only relative, web and mail links are allowed, which
+// keeps javascript: and data: URLs out of the page
+func safeURL(href string) bool {
+	u, err := url.Parse(href)
+	return err == nil && safeSchemes[strings.ToLower(u.Scheme)]
+}
+
+func isWordByte(c byte) bool {
+	return c == '_' || c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= 0x80
+}
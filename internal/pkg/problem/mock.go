@@ -0,0 +1,263 @@
+// Mock interviews, a timed run over several
+// problems with one overall time budget
+
+package problem
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+)
+
+var (
+	ErrMockCount   = errors.New("Mock interviews have between 1 and 10 problems")
+	ErrMockBudget  = errors.New("Time budget must be positive")
+	ErrMockPool    = errors.New("Not enough problems for a mock interview")
+	ErrMockActive  = errors.New("A mock interview is already running")
+	ErrNoMock      = errors.New("No mock interview is running")
+	ErrMockExpired = errors.New("Mock interview time is up")
+)
+
+const (
+	defaultMockCount   = 3
+	mockTimePerProblem = 30 * 60 // Default budget per problem in seconds
+)
+
+type Mock struct {
+	Id       int64 `json:"id"`
+	Started  int64 `json:"started"`  // Unix time the run started
+	Deadline int64 `json:"deadline"` // Unix time the run ends
+	Finished int64 `json:"finished"` // Unix time the run ended, 0 while running
+	Count    int64 `json:"count"`    // Number of problems
+	Score    int64 `json:"score"`    // Percentage of problems solved
+}
+
+type MockResult struct {
+	Position int64  `json:"position"`
+	Problem  int64  `json:"problem"`
+	Title    string `json:"title"`
+	Session  int64  `json:"session"` // Session id, 0 if not submitted
+	Date     int64  `json:"date"`
+	Time     int64  `json:"time"`
+	Solved   bool   `json:"solved"`
+	Grade    Grade  `json:"grade,omitempty"` // Left out if not submitted
+	Skipped  bool   `json:"skipped"`         // Archived before it was submitted
+}
+
+// Returned while a run is going on
+type MockStep struct {
+	Mock      Mock    `json:"mock"`
+	Position  int64   `json:"position"`
+	Problem   Problem `json:"problem"`   // Solutions are not revealed
+	Remaining int64   `json:"remaining"` // Seconds left in the run
+}
+
+// Returned once a run is over
+type MockSummary struct {
+	Mock     Mock         `json:"mock"`
+	Problems []MockResult `json:"problems"`
+	Solved   int64        `json:"solved"`
+}
+
+// Load the users running mock interview, runs
+// past their deadline are finished first
+func (b *Box) runningMock(user int64) (m Mock, err error) {
+	if m, err = b.activeMock(user); err == sql.ErrNoRows {
+		return m, ErrNoMock
+	} else if err != nil {
+		return
+	}
+	if time.Now().Unix() >= m.Deadline {
+		var summary MockSummary
+		if summary, err = b.finishMock(m); err == nil {
+			m, err = summary.Mock, ErrMockExpired
+		}
+	}
+	return
+}
+
+// End a run and compute its score
+func (b *Box) finishMock(m Mock) (MockSummary, error) {
+	summary, err := b.mockSummary(m)
+	if err != nil {
+		return summary, err
+	}
+	m.Finished = time.Now().Unix()
+	// Skipped problems do not count against the score
+	count := m.Count
+	for _, res := range summary.Problems {
+		if res.Skipped {
+			count--
+		}
+	}
+	if count > 0 {
+		m.Score = summary.Solved * 100 / count
+	}
+	summary.Mock = m
+	return summary, b.updateMock(m)
+}
+
+func (b *Box) mockSummary(m Mock) (summary MockSummary, err error) {
+	summary.Mock = m
+	if summary.Problems, err = b.mockResults(m.Id); err != nil {
+		return
+	}
+	for _, res := range summary.Problems {
+		if res.Solved {
+			summary.Solved++
+		}
+	}
+	return
+}
+
+// Return the next problem of a run, or its summary
+// if the run is over. Problems archived during the
+// run are skipped.
+func (b *Box) mockStep(m Mock) (interface{}, error) {
+	results, err := b.mockResults(m.Id)
+	if err != nil {
+		return nil, err
+	}
+	for _, res := range results {
+		if res.Session != 0 || res.Skipped {
+			continue
+		}
+		p, err := b.getProblem(res.Problem)
+		if err != nil {
+			return nil, err
+		}
+		p.Solution, p.SolutionHTML = "", ""
+		return MockStep{m, res.Position, p, m.Deadline - time.Now().Unix()}, nil
+	}
+	return b.finishMock(m)
+}
+
+// Implement server api functions
+
+func (b *Box) MockStart(r *http.Request, user int64) (interface{}, error) {
+	// Pick problems, optionally with a given tag
+	// and difficulty, and start a new run
+	count, err := formInt(r, "count", defaultMockCount)
+	if err != nil {
+		return nil, err
+	} else if count < 1 || count > 10 {
+		return nil, ErrMockCount
+	}
+	budget, err := formInt(r, "budget", count*mockTimePerProblem)
+	if err != nil {
+		return nil, err
+	} else if budget < 1 {
+		return nil, ErrMockBudget
+	}
+	if settings, err := b.getSettings(user); err != nil {
+		return nil, err
+	} else if settings.Paused != 0 {
+		return nil, ErrPaused
+	}
+	if _, err := b.runningMock(user); err == nil {
+		return nil, ErrMockActive
+	} else if err != ErrNoMock && err != ErrMockExpired {
+		return nil, err
+	}
+	difficulty, err := ParseDifficulty(r.FormValue("difficulty"))
+	if err != nil {
+		return nil, err
+	}
+	pool, err := b.mockPool(user, count, formTag(r), difficulty)
+	if err != nil {
+		return nil, err
+	} else if int64(len(pool)) < count {
+		return nil, ErrMockPool
+	}
+	now := time.Now().Unix()
+	m, err := b.createMock(user, Mock{Started: now, Deadline: now + budget, Count: count}, pool)
+	if err != nil {
+		return nil, err
+	}
+	return b.mockStep(m)
+}
+
+func (b *Box) MockNext(r *http.Request, user int64) (interface{}, error) {
+	// Current problem of the running mock
+	m, err := b.runningMock(user)
+	if err != nil {
+		return nil, err
+	}
+	return b.mockStep(m)
+}
+
+func (b *Box) MockSubmit(r *http.Request, user int64) (interface{}, error) {
+	// Submit the current problem and move on
+	m, err := b.runningMock(user)
+	if err == ErrMockExpired {
+		summary, err := b.mockSummary(m)
+		if err != nil {
+			return nil, err
+		}
+		return summary, ErrMockExpired
+	} else if err != nil {
+		return nil, err
+	}
+	results, err := b.mockResults(m.Id)
+	if err != nil {
+		return nil, err
+	}
+	// Time is counted from the previous submission
+	last := m.Started
+	for _, res := range results {
+		if res.Session != 0 {
+			last = res.Date
+			continue
+		} else if res.Skipped {
+			continue
+		}
+		var sess Session
+		sess.Problem = res.Problem
+		sess.User = user
+		sess.Date = time.Now().Unix()
+		sess.Code = strings.Trim(r.FormValue("code"), " ")
+		if sess.Time = sess.Date - last; sess.Time < 1 {
+			sess.Time = 1
+		}
+		if sess.Grade, err = formGrade(r); err != nil {
+			return nil, err
+		}
+		id, _, err := b.submit(sess)
+		if err != nil {
+			return nil, err
+		}
+		if err := b.linkMockSession(m.Id, res.Position, id); err != nil {
+			return nil, err
+		}
+		break
+	}
+	return b.mockStep(m)
+}
+
+func (b *Box) MockFinish(r *http.Request, user int64) (interface{}, error) {
+	// End the running mock early
+	m, err := b.runningMock(user)
+	if err == ErrMockExpired {
+		return b.mockSummary(m)
+	} else if err != nil {
+		return nil, err
+	}
+	return b.finishMock(m)
+}
+
+func (b *Box) MockGet(r *http.Request, user int64) (interface{}, error) {
+	// Summary of a past run
+	id, err := formInt(r, "id", 0)
+	if err != nil {
+		return nil, err
+	}
+	m, err := b.getMock(id, user)
+	if err == sql.ErrNoRows {
+		return nil, ErrNoMock
+	} else if err != nil {
+		return nil, err
+	}
+	return b.mockSummary(m)
+}
@@ -0,0 +1,118 @@
+// Problems belong to the user who created them,
+// other users can suggest edits to the owner
+
+package problem
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+var (
+	ErrForbidden           = errors.New("Only the owner of a problem can do this")
+	ErrSuggestionNotExists = errors.New("Suggestion does not exist")
+	ErrSuggestionClosed    = errors.New("Suggestion was already accepted or rejected")
+)
+
+// Only the first user is an admin, as in the auth package
+const adminUser = 1
+
+const (
+	SuggestionPending  = "pending"
+	SuggestionAccepted = "accepted"
+	SuggestionRejected = "rejected"
+)
+
+type Suggestion struct {
+	Id      int64   `json:"id"`
+	Author  int64   `json:"author"`
+	Date    int64   `json:"date"`
+	Status  string  `json:"status"`
+	Problem Problem `json:"problem"` // Proposed problem
+}
+
+func isAdmin(user int64) bool {
+	return user == adminUser
+}
+
+// Problems without a known owner can only
+// be edited by admins
+func canEdit(p Problem, user int64) bool {
+	return isAdmin(user) || (p.Owner != 0 && p.Owner == user)
+}
+
+// Load a pending suggestion for a problem the
+// user may edit
+func (b *Box) openSuggestion(r *http.Request, user int64) (Suggestion, error) {
+	id, err := strconv.ParseInt(r.FormValue("id"), 10, 64)
+	if err != nil {
+		return Suggestion{}, err
+	}
+	s, err := b.getSuggestion(id)
+	if err == sql.ErrNoRows {
+		return s, ErrSuggestionNotExists
+	} else if err != nil {
+		return s, err
+	} else if s.Status != SuggestionPending {
+		return s, ErrSuggestionClosed
+	}
+	if p, err := b.getProblem(s.Problem.Id); err != nil {
+		return s, err
+	} else if !canEdit(p, user) {
+		return s, ErrForbidden
+	}
+	return s, nil
+}
+
+// Implement server api functions
+
+func (b *Box) ProblemSuggest(r *http.Request, user int64) (interface{}, error) {
+	// Propose a change to a problem, takes the same
+	// values as ProblemUpdate and returns the suggestion id
+	id, err := strconv.ParseInt(r.FormValue("id"), 10, 64)
+	if err != nil {
+		return nil, err
+	}
+	p, err := b.getProblem(id)
+	if err == sql.ErrNoRows {
+		return nil, ErrProblemNotExists
+	} else if err != nil {
+		return nil, err
+	}
+	if p, err = formProblem(r, p); err != nil {
+		return nil, err
+	} else if p.Title == "" || p.Question == "" || p.Solution == "" {
+		return nil, ErrEmpty
+	}
+	return b.storeSuggestion(Suggestion{Author: user, Date: time.Now().Unix(), Status: SuggestionPending, Problem: p})
+}
+
+func (b *Box) ProblemSuggestions(r *http.Request, user int64) (interface{}, error) {
+	// List pending suggestions for the users problems,
+	// admins see the suggestions for all problems
+	return b.listSuggestions(user, isAdmin(user))
+}
+
+func (b *Box) SuggestionAccept(r *http.Request, user int64) (interface{}, error) {
+	// Apply the suggestion, the revision is
+	// attributed to the suggestions author
+	s, err := b.openSuggestion(r, user)
+	if err != nil {
+		return nil, err
+	}
+	if err := b.updateProblem(s.Problem, s.Author); err != nil {
+		return nil, err
+	}
+	return s.Problem.Id, b.setSuggestionStatus(s.Id, SuggestionAccepted)
+}
+
+func (b *Box) SuggestionReject(r *http.Request, user int64) (interface{}, error) {
+	s, err := b.openSuggestion(r, user)
+	if err != nil {
+		return nil, err
+	}
+	return nil, b.setSuggestionStatus(s.Id, SuggestionRejected)
+}
@@ -0,0 +1,60 @@
+// Vacation mode, pausing freezes the users
+// schedule until they resume
+
+package problem
+
+import (
+	"errors"
+	"net/http"
+	"time"
+)
+
+var (
+	ErrPaused    = errors.New("Schedule is paused")
+	ErrNotPaused = errors.New("Schedule is not paused")
+)
+
+// Number of the users days which started while the
+// schedule was paused. Due dates are shifted by whole
+// days, so they stay at the start of the users day.
+func (s Settings) pausedDays(now time.Time) int {
+	ys, ms, ds := s.startOfDay(time.Unix(s.Paused, 0)).Date()
+	ye, me, de := s.startOfDay(now).Date()
+	start := time.Date(ys, ms, ds, 0, 0, 0, 0, time.UTC)
+	end := time.Date(ye, me, de, 0, 0, 0, 0, time.UTC)
+	return int(end.Sub(start).Hours() / 24)
+}
+
+// Implement server api functions
+
+func (b *Box) SchedulePause(r *http.Request, user int64) (interface{}, error) {
+	// Record the start of the pause
+	s, err := b.getSettings(user)
+	if err != nil {
+		return nil, err
+	} else if s.Paused != 0 {
+		return nil, ErrPaused
+	}
+	s.Paused = time.Now().Unix()
+	return s, b.updateSettings(user, s)
+}
+
+func (b *Box) ScheduleResume(r *http.Request, user int64) (interface{}, error) {
+	// Shift all due dates by the paused days
+	s, err := b.getSettings(user)
+	if err != nil {
+		return nil, err
+	} else if s.Paused == 0 {
+		return nil, ErrNotPaused
+	}
+	days := s.pausedDays(time.Now())
+	loc := s.location()
+	err = b.shiftSchedule(user, func(due int64) int64 {
+		return time.Unix(due, 0).In(loc).AddDate(0, 0, days).Unix()
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.Paused = 0
+	return s, b.updateSettings(user, s)
+}
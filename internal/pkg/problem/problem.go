@@ -5,6 +5,7 @@ package problem
 
 import (
 	"database/sql"
+	"log"
 	"net/http"
 	"strconv"
 	"strings"
@@ -12,25 +13,51 @@ import (
 )
 
 type Problem struct {
-	Id       int64  `json:"id"`
-	Title    string `json:"title"`
-	Question string `json:"question"`
-	Solution string `json:"solution"`
+	Id         int64      `json:"id"`
+	Title      string     `json:"title"`
+	Question   string     `json:"question"`
+	Solution   string     `json:"solution"`
+	Starter    string     `json:"starter"` // Code new attempts start from
+	Target     int64      `json:"target"`  // Target time in seconds, 0 if not set
+	Tags       []string   `json:"tags"`
+	Difficulty Difficulty `json:"difficulty"`
+	Owner      int64      `json:"owner"`    // User who created the problem, 0 if unknown
+	Archived   int64      `json:"archived"` // Unix time the problem was archived, 0 if active
+
+	// Sanitized HTML of the Markdown question and
+	// solution, rendered when the problem is loaded
+	QuestionHTML string `json:"question_html"`
+	SolutionHTML string `json:"solution_html"`
 }
 
 type Session struct {
-	Id      int64
-	Problem int64
-	User    int64
-	Date    int64
-	Code    string
-	Time    int64
-	Solved  bool
+	Id       int64  `json:"id"`
+	Problem  int64  `json:"problem"`
+	User     int64  `json:"user"`
+	Date     int64  `json:"date"`
+	Code     string `json:"code"`
+	Time     int64  `json:"time"`
+	Solved   bool   `json:"solved"`
+	Grade    Grade  `json:"grade"`
+	Practice bool   `json:"practice"` // Practice sessions do not affect the schedule
+	Hints    int64  `json:"hints"`    // Hints revealed before submitting
 }
 
 type Box struct {
 	// Contains problems
 	db *sql.DB
+	// Decides when problems are due, the
+	// weekly scheduler is used by default
+	Scheduler Scheduler
+	// Judges submissions against test cases,
+	// submissions are not judged if nil
+	Judge Judge
+	// Problems failed this many times are
+	// leeches, handled by the leech policy
+	LeechThreshold int64
+	LeechPolicy    string
+	// Set if SQLite supports the search index
+	search bool
 }
 
 func NewBox(db *sql.DB) *Box {
@@ -39,27 +66,53 @@ func NewBox(db *sql.DB) *Box {
 	}
 	var b Box
 	b.db = db
+	search, err := initSearch(db)
+	if err != nil {
+		panic(err.Error())
+	} else if !search {
+		log.Print("SQLite lacks FTS5, problem search is disabled (build with -tags sqlite_fts5)")
+	}
+	b.search = search
+	b.Scheduler = WeeklyScheduler{}
+	b.LeechThreshold = DefaultLeechThreshold
+	b.LeechPolicy = LeechKeep
 	return &b
 }
 
 // Implement server api functions
 
 func (b *Box) ProblemUpdate(r *http.Request, user int64) (interface{}, error) {
-	// Update (or create) problem and return problem id
+	// Update (or create) problem and return problem id,
+	// only owners and admins can update a problem. New
+	// problems which look like existing ones are only
+	// created with force=1.
 	var id int64
 	var err error
 	if id, err = strconv.ParseInt(r.FormValue("id"), 10, 64); err != nil {
 		return nil, err
 	}
-	problem := Problem{id, r.FormValue("title"), r.FormValue("question"), r.FormValue("solution")}
-	problem.Title = strings.Trim(problem.Title, " \n")
-	problem.Question = strings.Trim(problem.Question, " \n")
-	problem.Solution = strings.Trim(problem.Solution, " \n")
+	problem := Problem{Id: id, Owner: user}
+	if id != -1 {
+		// Fields which are not given keep their value
+		if problem, err = b.getProblem(id); err == sql.ErrNoRows {
+			return nil, ErrProblemNotExists
+		} else if err != nil {
+			return nil, err
+		} else if !canEdit(problem, user) {
+			return nil, ErrForbidden
+		}
+	}
+	if problem, err = formProblem(r, problem); err != nil {
+		return nil, err
+	}
 	if id == -1 {
-		problem, err := b.createProblem(problem)
+		problem, duplicates, err := b.createProblem(problem, user, r.FormValue("force") == "1")
+		if err == ErrDuplicate {
+			return duplicates, err
+		}
 		return problem.Id, err
 	} else {
-		err := b.updateProblem(problem)
+		err := b.updateProblem(problem, user)
 		return problem.Id, err
 	}
 }
@@ -77,25 +130,127 @@ func (b *Box) ProblemSubmit(r *http.Request, user int64) (interface{}, error) {
 	if sess.Time, err = strconv.ParseInt(r.FormValue("time"), 10, 64); err != nil {
 		return nil, err
 	}
-	sess.Solved = r.FormValue("solved") != "0"
-	if err := b.storeSession(sess); err != nil {
+	if sess.Grade, err = formGrade(r); err != nil {
 		return nil, err
 	}
+	sess.Practice = r.FormValue("practice") == "1"
+	_, res, err := b.submit(sess)
+	return res, err
+}
+
+// Store a session and schedule the problem for later,
+// returns the session id and the new schedule. There
+// is no new schedule for practice sessions.
+func (b *Box) submit(sess Session) (int64, *Submission, error) {
+	if sess.Code == "" {
+		return 0, nil, ErrEmpty
+	}
+	// Archived problems are out of rotation
+	problem, err := b.getProblem(sess.Problem)
+	if err == sql.ErrNoRows {
+		return 0, nil, ErrProblemNotExists
+	} else if err != nil {
+		return 0, nil, err
+	} else if problem.Archived != 0 {
+		return 0, nil, ErrArchived
+	}
+	// Only practice goes on while the schedule is paused
+	settings, err := b.getSettings(sess.User)
+	if err != nil {
+		return 0, nil, err
+	} else if settings.Paused != 0 && !sess.Practice {
+		return 0, nil, ErrPaused
+	}
+	sess, results, err := b.judge(sess)
+	if err != nil {
+		return 0, nil, err
+	}
+	sess.Solved = sess.Grade.Solved()
+	if sess.Hints, err = b.hintsUsed(sess.Problem, sess.User); err != nil {
+		return 0, nil, err
+	}
+	id, err := b.storeSession(sess)
+	if err != nil {
+		return id, nil, err
+	} else if err := b.resetHints(sess.Problem, sess.User); err != nil {
+		return id, nil, err
+	} else if err := b.storeResults(id, results); err != nil {
+		return id, nil, err
+	} else if sess.Practice {
+		return id, nil, nil
+	}
 	// Schedule problem for later
-	n := time.Duration(b.numSuccessfulAttempts(sess.Problem, user))
-	due := time.Now().Add(time.Hour*24*7*n + time.Hour)
-	return nil, b.scheduleProblem(sess.Problem, user, due.Unix())
+	sched, err := b.getSchedule(sess.Problem, sess.User)
+	if err == sql.ErrNoRows {
+		sched = newSchedule(sess.Problem, sess.User)
+	} else if err != nil {
+		return id, nil, err
+	}
+	history, err := b.sessionHistory(sess.Problem, sess.User)
+	if err != nil {
+		return id, nil, err
+	}
+	history = reviews(history)
+	sched.Buried = 0
+	if !sess.Solved {
+		sched = b.recordLapse(sched)
+	}
+	sched = b.Scheduler.Next(sched, history)
+	planned := sched.Interval
+	sched, adjustment := adjustForTime(sched, history, problem.Target)
+	if sched, err = b.balanceDue(sched, settings, time.Unix(sess.Date, 0)); err != nil {
+		return id, nil, err
+	}
+	sched = settings.alignDue(sched, time.Unix(sess.Date, 0))
+	if adjustment != nil {
+		adjustment.explain(sched.Interval, planned)
+	}
+	if err := b.scheduleProblem(sched); err != nil {
+		return id, nil, err
+	}
+	return id, &Submission{sched.Due, sched.Interval, adjustment, sched.Lapses >= b.LeechThreshold, results}, nil
 }
 
 func (b *Box) ProblemNext(r *http.Request, user int64) (interface{}, error) {
 	// Suggest the following: scheduled, not-attempted, false (write new problem)
-	if p, err := b.nextScheduledProblem(user); err == nil {
-		return p, err
-	} else if p, err = b.notScheduledProblem(user); err == nil {
-		return p, err
-	} else {
-		return false, nil
+	// Problems are only served while the users daily limits are not reached
+	// and can be limited to a tag
+	tag := formTag(r)
+	settings, err := b.getSettings(user)
+	if err != nil {
+		return nil, err
+	} else if settings.Paused != 0 {
+		return Status{StatusPaused, "Your schedule is paused, resume it to continue."}, nil
+	}
+	if err := b.releaseBuried(user); err != nil {
+		return nil, err
 	}
+	reviews, fresh, err := b.countToday(user, settings.startOfDay(time.Now()).Unix())
+	if err != nil {
+		return nil, err
+	}
+	var limited bool
+	if p, err := b.nextScheduledProblem(user, tag); err == nil {
+		if settings.ReviewsPerDay == 0 || reviews < settings.ReviewsPerDay {
+			return p, err
+		}
+		limited = true
+	}
+	// New problems get harder as the user succeeds more often
+	sessions, solved, err := b.recentSuccess(user, rampSessions)
+	if err != nil {
+		return nil, err
+	}
+	if p, err := b.notScheduledProblem(user, tag, rampDifficulty(sessions, solved)); err == nil {
+		if settings.NewPerDay == 0 || fresh < settings.NewPerDay {
+			return p, err
+		}
+		limited = true
+	}
+	if limited {
+		return Status{StatusDone, "You are done for today, come back tomorrow!"}, nil
+	}
+	return false, nil
 }
 
 func (b *Box) ProblemGet(r *http.Request, user int64) (interface{}, error) {
@@ -106,3 +261,60 @@ func (b *Box) ProblemGet(r *http.Request, user int64) (interface{}, error) {
 		return b.getProblem(id)
 	}
 }
+
+func (b *Box) ProblemHistory(r *http.Request, user int64) (interface{}, error) {
+	// List the users sessions for a problem
+	if id, err := strconv.ParseInt(r.FormValue("id"), 10, 64); err != nil {
+		return nil, err
+	} else {
+		return b.sessionHistory(id, user)
+	}
+}
+
+// Helpers
+
+// Apply the problem fields of a form to p, optional
+// fields which are not given keep their value
+func formProblem(r *http.Request, p Problem) (Problem, error) {
+	var err error
+	p.Title = strings.Trim(r.FormValue("title"), " \n")
+	p.Question = strings.Trim(r.FormValue("question"), " \n")
+	p.Solution = strings.Trim(r.FormValue("solution"), " \n")
+	if _, ok := r.Form["starter"]; ok {
+		p.Starter = strings.Trim(r.FormValue("starter"), " \n")
+	}
+	if p.Target, err = formInt(r, "target", p.Target); err != nil {
+		return p, err
+	} else if p.Target < 0 {
+		return p, ErrTarget
+	}
+	if _, ok := r.Form["difficulty"]; ok {
+		if p.Difficulty, err = ParseDifficulty(r.FormValue("difficulty")); err != nil {
+			return p, err
+		}
+	}
+	if _, ok := r.Form["tags"]; ok {
+		if p.Tags, err = parseTags(r.FormValue("tags")); err != nil {
+			return p, err
+		}
+	}
+	return p, nil
+}
+
+// Parse the grade of a submission, falling back
+// to the legacy solved value
+func formGrade(r *http.Request) (Grade, error) {
+	if r.FormValue("grade") != "" {
+		return ParseGrade(r.FormValue("grade"))
+	}
+	return legacyGrade(r.FormValue("solved")), nil
+}
+
+// Parse an optional integer form value,
+// which defaults to def if not given
+func formInt(r *http.Request, key string, def int64) (int64, error) {
+	if r.FormValue(key) == "" {
+		return def, nil
+	}
+	return strconv.ParseInt(r.FormValue(key), 10, 64)
+}
@@ -0,0 +1,113 @@
+// Every edit of a problem is kept as a revision,
+// so bad edits can be rolled back
+
+package problem
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+var (
+	ErrRevisionNotExists = errors.New("Revision does not exist")
+)
+
+type Revision struct {
+	Id      int64   `json:"id"`
+	Author  int64   `json:"author"` // User id, 0 if unknown
+	Date    int64   `json:"date"`
+	Problem Problem `json:"problem"` // Problem as of this revision
+}
+
+type RevisionDiff struct {
+	From       int64      `json:"from"`
+	To         int64      `json:"to"`
+	Title      []DiffLine `json:"title"`
+	Question   []DiffLine `json:"question"`
+	Solution   []DiffLine `json:"solution"`
+	Starter    []DiffLine `json:"starter"`
+	Target     []DiffLine `json:"target"`
+	Difficulty []DiffLine `json:"difficulty"`
+	Tags       []DiffLine `json:"tags"`
+}
+
+// Load a revision which belongs to the given problem
+func (b *Box) problemRevision(id, revision int64) (Revision, error) {
+	rev, err := b.getRevision(revision)
+	if err == sql.ErrNoRows || (err == nil && rev.Problem.Id != id) {
+		return rev, ErrRevisionNotExists
+	}
+	return rev, err
+}
+
+// Implement server api functions
+
+func (b *Box) ProblemRevisions(r *http.Request, user int64) (interface{}, error) {
+	// List revisions of a problem, newest first
+	if id, err := strconv.ParseInt(r.FormValue("id"), 10, 64); err != nil {
+		return nil, err
+	} else {
+		return b.listRevisions(id)
+	}
+}
+
+func (b *Box) ProblemDiff(r *http.Request, user int64) (interface{}, error) {
+	// Diff two revisions of a problem
+	var id, from, to int64
+	var err error
+	if id, err = strconv.ParseInt(r.FormValue("id"), 10, 64); err != nil {
+		return nil, err
+	} else if from, err = strconv.ParseInt(r.FormValue("from"), 10, 64); err != nil {
+		return nil, err
+	} else if to, err = strconv.ParseInt(r.FormValue("to"), 10, 64); err != nil {
+		return nil, err
+	}
+	a, err := b.problemRevision(id, from)
+	if err != nil {
+		return nil, err
+	}
+	c, err := b.problemRevision(id, to)
+	if err != nil {
+		return nil, err
+	}
+	x, y := a.Problem, c.Problem
+	return RevisionDiff{
+		From:       from,
+		To:         to,
+		Title:      diffLines(x.Title, y.Title),
+		Question:   diffLines(x.Question, y.Question),
+		Solution:   diffLines(x.Solution, y.Solution),
+		Starter:    diffLines(x.Starter, y.Starter),
+		Target:     diffLines(strconv.FormatInt(x.Target, 10), strconv.FormatInt(y.Target, 10)),
+		Difficulty: diffLines(x.Difficulty.String(), y.Difficulty.String()),
+		Tags:       diffLines(strings.Join(x.Tags, "\n"), strings.Join(y.Tags, "\n")),
+	}, nil
+}
+
+func (b *Box) ProblemRollback(r *http.Request, user int64) (interface{}, error) {
+	// Restore a problem to an earlier revision,
+	// which is stored as a new revision
+	var id, revision int64
+	var err error
+	if id, err = strconv.ParseInt(r.FormValue("id"), 10, 64); err != nil {
+		return nil, err
+	} else if revision, err = strconv.ParseInt(r.FormValue("revision"), 10, 64); err != nil {
+		return nil, err
+	}
+	p, err := b.getProblem(id)
+	if err == sql.ErrNoRows {
+		return nil, ErrProblemNotExists
+	} else if err != nil {
+		return nil, err
+	} else if !canEdit(p, user) {
+		return nil, ErrForbidden
+	}
+	rev, err := b.problemRevision(id, revision)
+	if err != nil {
+		return nil, err
+	}
+	return id, b.updateProblem(rev.Problem, user)
+}
@@ -0,0 +1,150 @@
+// Spaced repetition schedulers, which decide
+// when a user has to solve a problem again
+
+package problem
+
+import (
+	"time"
+)
+
+const (
+	defaultEase = 2.5
+	minEase     = 1.3
+	retryDelay  = time.Hour // Failed problems are retried after this delay
+)
+
+type Scheduler interface {
+	// Scheduling algorithm used by a box. Given
+	// the current schedule and the session history
+	// of a (user, problem) pair, ordered oldest
+	// first, return the next schedule. The last
+	// session is the one just submitted. Practice
+	// sessions are not part of the history. Solves
+	// with hints should count as weaker.
+	Next(prev Schedule, history []Session) Schedule
+}
+
+// Schedulers which can be selected by name
+var Schedulers = map[string]Scheduler{
+	"weekly": WeeklyScheduler{},
+	"sm2":    SM2Scheduler{},
+}
+
+type Schedule struct {
+	Problem  int64
+	User     int64
+	Due      int64   // Unix time the problem is due
+	Ease     float64 // SM-2 ease factor
+	Interval int64   // Days since the last review
+	Reps     int     // Successful reviews in a row
+
+	Lapses    int64 // Failed reviews
+	Suspended bool  // Suspended problems are not served
+	Buried    int64 // Unix time until which the problem is skipped
+}
+
+// Create the schedule for a problem the
+// user has not attempted before
+func newSchedule(id, user int64) Schedule {
+	return Schedule{Problem: id, User: user, Ease: defaultEase}
+}
+
+// Compute the schedule following a review at
+// time now. The quality ranges from 0 (blackout)
+// to 5 (perfect), anything below 3 is a failure.
+func (s Schedule) next(quality int, now time.Time) Schedule {
+	q := float64(quality)
+	s.Ease += 0.1 - (5-q)*(0.08+(5-q)*0.02)
+	if s.Ease < minEase {
+		s.Ease = minEase
+	}
+	if quality < 3 {
+		s.Reps = 0
+		s.Interval = 0
+		s.Due = now.Add(retryDelay).Unix()
+		return s
+	}
+	s.Reps++
+	switch s.Reps {
+	case 1:
+		s.Interval = 1
+	case 2:
+		s.Interval = 6
+	default:
+		s.Interval = int64(float64(s.Interval)*s.Ease + 0.5)
+	}
+	s.Due = now.Add(time.Hour * 24 * time.Duration(s.Interval)).Unix()
+	return s
+}
+
+// Drop the practice sessions from a history
+func reviews(history []Session) []Session {
+	var res []Session
+	for _, sess := range history {
+		if !sess.Practice {
+			res = append(res, sess)
+		}
+	}
+	return res
+}
+
+// Count the successful sessions since the
+// last failed one, ignoring practice sessions
+func numSuccessfulAttempts(history []Session) (num int) {
+	for _, sess := range history {
+		if sess.Practice {
+			continue
+		} else if sess.Solved {
+			num++
+		} else {
+			num = 0
+		}
+	}
+	return
+}
+
+// WeeklyScheduler schedules a problem one week
+// later for every successful attempt in a row.
+// Hard solves halve the interval, easy ones add
+// a week. Failed problems are retried after an
+// hour. Hints lower the grade of a solve.
+type WeeklyScheduler struct{}
+
+func (WeeklyScheduler) Next(prev Schedule, history []Session) Schedule {
+	if len(history) == 0 {
+		return prev
+	}
+	last := history[len(history)-1]
+	n := numSuccessfulAttempts(history)
+	prev.Reps = n
+	prev.Interval = 7 * int64(n)
+	switch last.effectiveGrade() {
+	case GradeHard:
+		prev.Interval /= 2
+	case GradeEasy:
+		prev.Interval += 7
+	}
+	prev.Due = time.Unix(last.Date, 0).Add(time.Hour*24*time.Duration(prev.Interval) + retryDelay).Unix()
+	return prev
+}
+
+// SM-2 quality of each grade
+var sm2Quality = map[Grade]int{
+	GradeAgain: 1,
+	GradeHard:  3,
+	GradeGood:  4,
+	GradeEasy:  5,
+}
+
+// SM2Scheduler grows the interval of each problem
+// by its ease factor, which adapts to how well
+// the user does. Hints lower the grade of a solve.
+type SM2Scheduler struct{}
+
+func (SM2Scheduler) Next(prev Schedule, history []Session) Schedule {
+	if len(history) == 0 {
+		return prev
+	}
+	last := history[len(history)-1]
+	return prev.next(sm2Quality[last.effectiveGrade()], time.Unix(last.Date, 0))
+}
@@ -0,0 +1,122 @@
+// Full-text search over problems and the
+// code of the users own sessions
+
+package problem
+
+import (
+	"errors"
+	"html"
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+var (
+	ErrQuery       = errors.New("Search query is empty")
+	ErrSearchLimit = errors.New("Search limit must be between 1 and 100")
+	ErrNoSearch    = errors.New("Search is not available on this server")
+)
+
+const (
+	defaultSearchLimit = 20
+	markStart          = "\x02" // Marks the start of a match in snippets
+	markEnd            = "\x03" // Marks the end of a match in snippets
+	codeSnippetLength  = 160    // Bytes of code shown around a match
+)
+
+type SearchResult struct {
+	Problem int64  `json:"problem"`
+	Title   string `json:"title"`
+	Session int64  `json:"session"` // Set if the match is in the code of a session
+	Snippet string `json:"snippet"` // HTML with matches wrapped in mark tags
+}
+
+// Turn the words of a query into a fts5 query
+// matching all of them, each word is quoted so
+// operators and punctuation do not change it
+func ftsQuery(terms []string) string {
+	quoted := make([]string, len(terms))
+	for i, term := range terms {
+		quoted[i] = `"` + strings.Replace(term, `"`, `""`, -1) + `"`
+	}
+	return strings.Join(quoted, " ")
+}
+
+// Escape a snippet, then turn the match markers into tags
+func highlight(snippet string) string {
+	snippet = html.EscapeString(snippet)
+	snippet = strings.Replace(snippet, markStart, "<mark>", -1)
+	return strings.Replace(snippet, markEnd, "</mark>", -1)
+}
+
+// Cut the code around the first match of
+// the terms and mark all matches in it
+func codeSnippet(code string, terms []string) string {
+	quoted := make([]string, len(terms))
+	for i, term := range terms {
+		quoted[i] = regexp.QuoteMeta(term)
+	}
+	match := regexp.MustCompile("(?i)" + strings.Join(quoted, "|"))
+	loc := match.FindStringIndex(code)
+	if loc == nil {
+		return ""
+	}
+	start, end := loc[0]-codeSnippetLength/2, loc[0]+codeSnippetLength/2
+	if start < 0 {
+		start = 0
+	}
+	if end > len(code) {
+		end = len(code)
+	}
+	// Do not cut runes in half
+	for start > 0 && !isRuneStart(code[start]) {
+		start--
+	}
+	for end < len(code) && !isRuneStart(code[end]) {
+		end++
+	}
+	snippet := match.ReplaceAllString(code[start:end], markStart+"$0"+markEnd)
+	if start > 0 {
+		snippet = "…" + snippet
+	}
+	if end < len(code) {
+		snippet += "…"
+	}
+	return highlight(snippet)
+}
+
+func isRuneStart(c byte) bool {
+	return c&0xC0 != 0x80
+}
+
+// Implement server api functions
+
+func (b *Box) ProblemSearch(r *http.Request, user int64) (interface{}, error) {
+	// Search problems ranked by relevance, with code=1
+	// the code of the users sessions is searched too
+	if !b.search {
+		return nil, ErrNoSearch
+	}
+	terms := strings.Fields(r.FormValue("query"))
+	if len(terms) == 0 {
+		return nil, ErrQuery
+	}
+	limit, err := formInt(r, "limit", defaultSearchLimit)
+	if err != nil {
+		return nil, err
+	} else if limit < 1 || limit > 100 {
+		return nil, ErrSearchLimit
+	}
+	results, err := b.searchProblems(ftsQuery(terms), limit)
+	if err != nil || r.FormValue("code") != "1" {
+		return results, err
+	}
+	code, err := b.searchSessions(user, terms, limit)
+	if err != nil {
+		return nil, err
+	}
+	for i := range code {
+		code[i].Snippet = codeSnippet(code[i].Snippet, terms)
+	}
+	return append(results, code...), nil
+}
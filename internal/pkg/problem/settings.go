@@ -0,0 +1,101 @@
+// Per-user settings which control how
+// problems are served
+
+package problem
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+)
+
+var (
+	ErrLimit    = errors.New("Limits may not be negative")
+	ErrTimezone = errors.New("Unknown timezone")
+	ErrDayStart = errors.New("Day start must be an hour between 0 and 23")
+)
+
+type Settings struct {
+	ReviewsPerDay int64  `json:"reviews_per_day"` // Scheduled problems per day, 0 is unlimited
+	NewPerDay     int64  `json:"new_per_day"`     // Not attempted problems per day, 0 is unlimited
+	Paused        int64  `json:"paused"`          // Unix time the schedule was paused, 0 if active
+	Timezone      string `json:"timezone"`        // IANA timezone, the servers timezone if empty
+	DayStart      int64  `json:"day_start"`       // Hour at which the users day starts
+}
+
+// Returned by ProblemNext instead of a problem
+type Status struct {
+	Status  string `json:"status"`
+	Message string `json:"message"`
+}
+
+const (
+	StatusDone   = "done"   // Daily limits are reached
+	StatusPaused = "paused" // The users schedule is paused
+)
+
+func (s Settings) location() *time.Location {
+	if s.Timezone == "" {
+		return time.Local
+	} else if loc, err := time.LoadLocation(s.Timezone); err == nil {
+		return loc
+	}
+	return time.Local
+}
+
+// Start of the users day containing t
+func (s Settings) startOfDay(t time.Time) time.Time {
+	t = t.In(s.location())
+	y, m, d := t.Date()
+	start := time.Date(y, m, d, int(s.DayStart), 0, 0, 0, t.Location())
+	if t.Before(start) {
+		start = start.AddDate(0, 0, -1)
+	}
+	return start
+}
+
+// Make a schedule due from the start of the users
+// day, interval calendar days after the review.
+// Problems due within the same day are not moved.
+func (s Settings) alignDue(sched Schedule, reviewed time.Time) Schedule {
+	if sched.Interval > 0 {
+		sched.Due = s.startOfDay(reviewed).AddDate(0, 0, int(sched.Interval)).Unix()
+	}
+	return sched
+}
+
+// Implement server api functions
+
+func (b *Box) SettingsGet(r *http.Request, user int64) (interface{}, error) {
+	return b.getSettings(user)
+}
+
+func (b *Box) SettingsUpdate(r *http.Request, user int64) (interface{}, error) {
+	// Update settings, values which are not
+	// given are left unchanged
+	s, err := b.getSettings(user)
+	if err != nil {
+		return nil, err
+	}
+	if s.ReviewsPerDay, err = formInt(r, "reviews_per_day", s.ReviewsPerDay); err != nil {
+		return nil, err
+	}
+	if s.NewPerDay, err = formInt(r, "new_per_day", s.NewPerDay); err != nil {
+		return nil, err
+	}
+	if s.DayStart, err = formInt(r, "day_start", s.DayStart); err != nil {
+		return nil, err
+	}
+	if _, ok := r.Form["timezone"]; ok {
+		s.Timezone = strings.Trim(r.FormValue("timezone"), " ")
+	}
+	if s.ReviewsPerDay < 0 || s.NewPerDay < 0 {
+		return nil, ErrLimit
+	} else if s.DayStart < 0 || s.DayStart > 23 {
+		return nil, ErrDayStart
+	} else if _, err := time.LoadLocation(s.Timezone); err != nil {
+		return nil, ErrTimezone
+	}
+	return s, b.updateSettings(user, s)
+}
@@ -0,0 +1,53 @@
+// Problems can be tagged with categories,
+// which can be used to focus practice
+
+package problem
+
+import (
+	"errors"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+var (
+	ErrTag = errors.New("Tags may be at most 32 characters long")
+)
+
+const maxTagLength = 32
+
+type TagCount struct {
+	Name     string `json:"name"`
+	Problems int64  `json:"problems"`
+}
+
+// Parse a comma separated list of tags, tags are
+// lower case and each tag is kept only once
+func parseTags(str string) ([]string, error) {
+	tags := []string{}
+	seen := make(map[string]bool)
+	for _, tag := range strings.Split(str, ",") {
+		tag = strings.ToLower(strings.Trim(tag, " \n"))
+		if tag == "" || seen[tag] {
+			continue
+		} else if len(tag) > maxTagLength {
+			return nil, ErrTag
+		}
+		seen[tag] = true
+		tags = append(tags, tag)
+	}
+	sort.Strings(tags)
+	return tags, nil
+}
+
+// Normalize the tag filter of a request
+func formTag(r *http.Request) string {
+	return strings.ToLower(strings.Trim(r.FormValue("tag"), " "))
+}
+
+// Implement server api functions
+
+func (b *Box) ProblemTags(r *http.Request, user int64) (interface{}, error) {
+	// List tags with the number of problems
+	return b.listTags()
+}
@@ -0,0 +1,95 @@
+// Shorten intervals of problems which took
+// much longer to solve than expected
+
+package problem
+
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	slowFactor  = 1.5 // Solves slower than this times the reference are adjusted
+	minTimeRate = 0.5 // Intervals shrink by at most this factor
+)
+
+// Result of submitting a session
+type Submission struct {
+	Due        int64        `json:"due"`        // Unix time the problem is due next
+	Interval   int64        `json:"interval"`   // Days until the problem is due
+	Adjustment *Adjustment  `json:"adjustment"` // Set if the solve time moved the due date
+	Leech      bool         `json:"leech"`      // Set if the problem is failed repeatedly
+	Tests      []TestResult `json:"tests"`      // Judged test results, if the problem has tests
+}
+
+type Adjustment struct {
+	Reason    string  `json:"reason"`    // Human readable explanation
+	Factor    float64 `json:"factor"`    // Factor applied to the interval
+	Time      int64   `json:"time"`      // Seconds the solve took
+	Reference int64   `json:"reference"` // Seconds the solve was expected to take
+}
+
+// Average time of the previous successful
+// sessions, or 0 if there are none
+func averageSolveTime(history []Session) int64 {
+	var sum, n int64
+	for _, sess := range history {
+		if sess.Solved && !sess.Practice {
+			sum += sess.Time
+			n++
+		}
+	}
+	if n == 0 {
+		return 0
+	}
+	return sum / n
+}
+
+// Shorten the interval of the schedule if the last
+// session took much longer than the target time or
+// than the previous solves of this user.
+func adjustForTime(s Schedule, history []Session, target int64) (Schedule, *Adjustment) {
+	if len(history) == 0 || s.Interval < 2 {
+		return s, nil
+	}
+	last := history[len(history)-1]
+	if !last.Solved {
+		return s, nil
+	}
+	var adj *Adjustment
+	check := func(reference int64, reason string) {
+		if reference < 1 || float64(last.Time) <= slowFactor*float64(reference) {
+			return
+		}
+		factor := float64(reference) / float64(last.Time)
+		if factor < minTimeRate {
+			factor = minTimeRate
+		}
+		if adj == nil || factor < adj.Factor {
+			adj = &Adjustment{reason, factor, last.Time, reference}
+		}
+	}
+	average := averageSolveTime(history[:len(history)-1])
+	check(target, fmt.Sprintf("Took %d minutes against a target of %d minutes", minutes(last.Time), minutes(target)))
+	check(average, fmt.Sprintf("Took %d minutes, previous solves averaged %d minutes", minutes(last.Time), minutes(average)))
+	if adj == nil {
+		return s, nil
+	}
+	interval := int64(float64(s.Interval)*adj.Factor + 0.5)
+	if interval < 1 {
+		interval = 1
+	}
+	s.Due -= int64(time.Duration(s.Interval-interval) * time.Hour * 24 / time.Second)
+	s.Interval = interval
+	return s, adj
+}
+
+// Complete the reason with the final interval, which is
+// only known once the due date has been balanced
+func (a *Adjustment) explain(interval, planned int64) {
+	a.Reason += fmt.Sprintf(", next review in %d instead of %d days", interval, planned)
+}
+
+func minutes(seconds int64) int64 {
+	return (seconds + 30) / 60
+}
@@ -43,7 +43,7 @@ ui.problem = document.getElementById("problem");
 ui.console = document.getElementById("console");
 ui.modal = document.getElementById("modal");
 ui.buttons = {};
-["run", "correct", "wrong"].forEach(btn => ui.buttons[btn] = document.getElementById(`button-${btn}`));
+["run", "hint", "correct", "wrong"].forEach(btn => ui.buttons[btn] = document.getElementById(`button-${btn}`));
 
 
 // App ui logic
@@ -59,6 +59,14 @@ function showModal(title, text, button, callback) {
   ui.modal.show();
 }
 
+function escapeHtml(str) {
+  // Modal text is html, user content
+  // has to be escaped
+  const elem = document.createElement("span");
+  elem.textContent = "".concat(str);
+  return elem.innerHTML;
+}
+
 function showError(msg) {
   showModal("An Error Occurred", msg, "Done", () => {});
 }
@@ -96,30 +104,69 @@ function run() {
 }
 ui.buttons.run.onclick = run;
 
+// Error of the server for edits by users
+// who do not own the problem
+const errForbidden = "Only the owner of a problem can do this";
+
 function save() {
-  // Save the problem when edited
-  apiPost("/problem/update", {
+  // Save the problem when edited, edits to
+  // problems of other users become suggestions
+  const problem = {
     id: problemId,
     title: ui.problem.title,
     question: ui.problem.question,
     solution: ui.problem.solution,
-  }, res => {
-    if (res.error) {
+  };
+  if (forceCreate) problem.force = "1";
+  apiPost("/problem/update", problem, res => {
+    if (res.error === errForbidden && problemId !== -1) {
+      suggest(problem);
+    } else if (res.error && Array.isArray(res.value)) {
+      // Similar problems exist, saving
+      // again creates the problem anyway
+      forceCreate = true;
+      const titles = res.value.map(dup => escapeHtml(dup.title)).join(", ");
+      showModal(
+        "Possible Duplicate",
+        `Similar problems exist: ${titles}. Save again to create it anyway.`,
+        "Done",
+        () => {}
+      );
+    } else if (res.error) {
       showError(res.error);
     } else {
-      // Update problem id
+      // Update problem id and render the
+      // saved markdown
+      forceCreate = false;
       problemId = +res.value;
+      loadProblem(problemId);
     }
   });
 }
 ui.problem.onsave = save;
 
+function suggest(problem) {
+  apiPost("/problem/suggest", problem, res => {
+    if (res.error) {
+      showError(res.error);
+    } else {
+      showModal(
+        "Edit Suggested",
+        "Your edit was sent to the owner of this problem.",
+        "Done",
+        () => {}
+      );
+    }
+  });
+}
+
 function newProblem() {
   // Create a new problem
   ui.problem.setTitle("Hello World");
   ui.problem.setQuestion("Write a new problem here...");
   ui.problem.setSolution("Write the solution here...");
   problemId = -1;
+  forceCreate = false;
   ui.timer.reset();
   ui.editor.setValue("");
   ui.editor.clearHistory();
@@ -162,9 +209,15 @@ function submit(correct) {
         if (res.error) {
           showError(res.error);
         } else {
+          let text = correct ? "Great work today, see you tomorrow!" : "Practice makes perfect, try again tomorrow!";
+          if (res.value && res.value.adjustment) text += ` ${res.value.adjustment.reason}.`;
+          if (res.value && res.value.tests && res.value.tests.length) {
+            const passed = res.value.tests.filter(t => t.passed).length;
+            text += ` Passed ${passed} of ${res.value.tests.length} tests.`;
+          }
           showModal(
             "Session Recorded",
-            correct ? "Great work today, see you tomorrow!" : "Practice makes perfect, try again tomorrow!",
+            text,
             "Attempt Next Problem",
             () => window.location.href = "/app"
           );
@@ -173,6 +226,26 @@ function submit(correct) {
     }
   });
 }
+
+function hint() {
+  // Reveal the next hint, which weakens the solve
+  if (problemId === -1) return;
+  apiPost("/problem/hint", {id: problemId}, res => {
+    if (res.error) {
+      showError(res.error);
+    } else {
+      const hints = res.value.hints;
+      showModal(
+        `Hint ${hints.length} of ${res.value.total}`,
+        escapeHtml(hints[hints.length - 1]),
+        "Done",
+        () => {}
+      );
+    }
+  });
+}
+ui.buttons.hint.onclick = hint;
+
 ui.buttons.correct.onclick = () => submit(true);
 ui.buttons.wrong.onclick = () => submit(false);
 
@@ -186,12 +259,26 @@ function loadProblem(id) {
         "Try Again",
         () => { loadProblem(id) }
       );
+    } else if (res.value && res.value.status) {
+      // No problem is served right now
+      showModal(
+        "No Problem Scheduled",
+        res.value.message,
+        "Done",
+        () => {}
+      );
     } else if (res.value) {
       // A new problem is already scheduled
       problemId = +res.value.id;
       ui.problem.setTitle(res.value.title);
-      ui.problem.setQuestion(res.value.question);
-      ui.problem.setSolution(res.value.solution);
+      ui.problem.setQuestion(res.value.question, res.value.question_html);
+      ui.problem.setSolution(res.value.solution, res.value.solution_html);
+      if (!id && res.value.starter) {
+        // New attempts start from the starter code
+        ui.editor.setValue(res.value.starter);
+        ui.editor.clearHistory();
+        saveDraft();
+      }
     } else {
       showModal(
         "No Problem Scheduled",
@@ -223,5 +310,6 @@ function initApp() {
 // Load problem from server
 
 let problemId = -1; // Is initialized by server, -1 <=> this will be a new problem
+let forceCreate = false; // Set once the user was warned about duplicates
 
 initApp();
@@ -138,59 +138,69 @@ class ProblemWidget extends HTMLElement {
     this.buttonEdit = this.childNodes[5];
     this.buttonNew = this.childNodes[6];
     this.edit = false;
+    this.source = {question: "", solution: ""}; // Markdown of question and solution
     // Actions
     this.buttonDone.onclick = () => {this.setDone()}
     this.buttonEdit.onclick = () => {this.toggleEdit()};
     this.buttonNew.onclick = () => {this.onnew()};
     // Initial draw
-    this.titleElem.innerHTML = this.hasAttribute("title") ? this.getAttribute("title") : "";
-    this.questionElem.innerHTML = this.hasAttribute("question") ? this.getAttribute("question") : "";
-    this.solutionElem.innerHTML = this.hasAttribute("solution") ? this.getAttribute("solution") : "";
+    this.setTitle(this.hasAttribute("title") ? this.getAttribute("title") : "");
+    this.setQuestion(this.hasAttribute("question") ? this.getAttribute("question") : "");
+    this.setSolution(this.hasAttribute("solution") ? this.getAttribute("solution") : "");
   }
 
   setTitle(str) {
-    this.titleElem.innerHTML = "".concat(str);
+    this.titleElem.textContent = "".concat(str);
   }
 
-  setQuestion(str) {
-    this.questionElem.innerHTML = "".concat(str);
+  setQuestion(str, html) {
+    this.source.question = "".concat(str);
+    this.show(this.questionElem, this.source.question, html);
   }
 
-  setSolution(str) {
-    this.solutionElem.innerHTML = "".concat(str);
+  setSolution(str, html) {
+    this.source.solution = "".concat(str);
+    this.show(this.solutionElem, this.source.solution, html);
+  }
+
+  show(elem, str, html) {
+    // Only html sanitized by the server is
+    // rendered, anything else is shown as text
+    elem.style.whiteSpace = html === undefined ? "pre-wrap" : "";
+    if (html === undefined) {
+      elem.textContent = str;
+    } else {
+      elem.innerHTML = html;
+    }
   }
 
   get title() {
-    return "".concat(this.titleElem.innerHTML);
+    return "".concat(this.titleElem.textContent);
   }
 
   get question() {
-    return "".concat(this.questionElem.innerHTML);
+    return this.edit ? "".concat(this.questionElem.innerText) : this.source.question;
   }
 
   get solution() {
-    return "".concat(this.solutionElem.innerHTML);
-  }
-
-  sanitize () {
-    [this.titleElem, this.questionElem, this.solutionElem].forEach(elem => {
-      elem.innerHTML = "".concat(elem.innerHTML).replace(
-        /\<[^>/]+\>/g, "\n").replace(
-        /\<[^>]+\>/g, "");
-    });
+    return this.edit ? "".concat(this.solutionElem.innerText) : this.source.solution;
   }
 
   toggleEdit() {
     this.edit = !this.edit;
     let contenteditable = "false";
     if (this.edit) {
+      // Edit the markdown source
       contenteditable = "true";
+      this.show(this.questionElem, this.source.question);
+      this.show(this.solutionElem, this.source.solution);
       this.buttonEdit.classList.add("icon-save");
       this.buttonEdit.classList.remove("icon-edit");
     } else {
       this.buttonEdit.classList.add("icon-edit");
       this.buttonEdit.classList.remove("icon-save");
-      this.sanitize();
+      this.setQuestion(this.questionElem.innerText);
+      this.setSolution(this.solutionElem.innerText);
       this.onsave();
     }
     this.buttonEdit.innerHTML = this.edit ? "Save" : "Edit";